@@ -0,0 +1,116 @@
+package config
+
+import (
+  "fmt"
+  "reflect"
+  "strings"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+// Returns a config that only holds defaults. Merge it with loaded configs
+//  in any order, the defaults always stay underneath their values.
+func Defaults(data ConfigData) *Config {
+  return defaultsConfig(data, "")
+}
+
+// Name is the struct the defaults were read from, if any
+func defaultsConfig(data ConfigData, name string) *Config {
+  defaults := make(ConfigData, len(data))
+
+  for k, v := range data {
+    defaults[strings.ToLower(k)] = deepCopy(v)
+  }
+
+  origins := dataOrigins(defaults, constantOrigin(Origin{Kind: DefaultOrigin, Name: name}))
+
+  return &Config{Data: ConfigData{}, defaults: defaults, origins: origins}
+}
+
+// Same as Defaults but the values are read from `default` tags of a struct.
+//  Keys are named the same way Unmarshal names them, nested structs become
+//  nested maps. Accepts a struct or a pointer to one.
+func DefaultsFromStruct(v interface{}) (*Config, *errtrace.Error) {
+  t := reflect.TypeOf(v)
+
+  if t == nil || indirectType(t).Kind() != reflect.Struct {
+    return nil, errtrace.New(fmt.Sprintf("Could not read defaults from %T: expected a struct", v))
+  }
+
+  return defaultsConfig(defaultsFromType(indirectType(t)), indirectType(t).String()), nil
+}
+
+func DefaultsFromStructP(v interface{}) *Config {
+  c, err := DefaultsFromStruct(v)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
+
+// Tells whether the value under key comes from the defaults rather than
+//  from a loaded file, env var or Set
+func (c *Config) IsDefault(key string) bool {
+  c.mutex.RLock()
+  defer c.mutex.RUnlock()
+
+  _, err := c.Data.get(key)
+
+  if err == nil || c.Data.shadows(key) {
+    return false
+  }
+
+  _, err = c.defaults.get(key)
+
+  return err == nil
+}
+
+// A copy of the data with the defaults merged underneath
+func (c *Config) effectiveData() ConfigData {
+  data, defaults := c.snapshot()
+
+  return mergeData(defaults, data)
+}
+
+func defaultsFromType(t reflect.Type) ConfigData {
+  data := ConfigData{}
+
+  for i := 0; i < t.NumField(); i++ {
+    field := t.Field(i)
+
+    if field.PkgPath != "" {
+      continue
+    }
+
+    name, tagged := fieldKey(field)
+
+    if name == "-" {
+      continue
+    }
+
+    fieldType := indirectType(field.Type)
+
+    if field.Anonymous && tagged == false && fieldType.Kind() == reflect.Struct {
+      for k, v := range defaultsFromType(fieldType) {
+        data[k] = v
+      }
+
+      continue
+    }
+
+    value, hasDefault := field.Tag.Lookup("default")
+
+    if hasDefault {
+      data[name] = value
+    } else if fieldType.Kind() == reflect.Struct {
+      nested := defaultsFromType(fieldType)
+
+      if len(nested) > 0 {
+        data[name] = deepCopy(nested)
+      }
+    }
+  }
+
+  return data
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+  "fmt"
+  "sort"
+  "reflect"
+  "strings"
+  "encoding/json"
+)
+
+type ChangeKind string
+
+const (
+  Added ChangeKind = "added"
+  Removed ChangeKind = "removed"
+  Changed ChangeKind = "changed"
+)
+
+// A key that differs between two configs. Path is the dotted path Get
+//  reads it with. Old is nil for added keys and New for removed ones.
+type Change struct {
+  Kind ChangeKind
+  Path string
+  Old interface{}
+  New interface{}
+}
+
+type Changes []Change
+
+// Compares the effective values of two configs, defaults included, and
+//  lists every added, removed or changed key sorted by path. Nested maps
+//  and lists are compared item by item, a whole added or removed section
+//  is one change. Values of keys that are secret in either config are
+//  redacted.
+func Diff(a *Config, b *Config) Changes {
+  secrets := append(a.secretKeys(), b.secretKeys()...)
+  changes := Changes{}
+
+  diffValues("", a.effectiveData(), b.effectiveData(), secrets, &changes)
+
+  return changes
+}
+
+// One line per change, + for added, - for removed and ~ for changed keys
+//
+//    ~ db.host: "localhost" -> "db.internal"
+//    + db.pool.idle: 3
+//    - servers.2: "c"
+func (changes Changes) String() string {
+  lines := make([]string, len(changes))
+
+  for i, change := range changes {
+    switch change.Kind {
+      case Added:
+        lines[i] = fmt.Sprintf("+ %s: %s", change.Path, renderedValue(change.New))
+      case Removed:
+        lines[i] = fmt.Sprintf("- %s: %s", change.Path, renderedValue(change.Old))
+      default:
+        lines[i] = fmt.Sprintf("~ %s: %s -> %s", change.Path, renderedValue(change.Old), renderedValue(change.New))
+    }
+  }
+
+  return strings.Join(lines, "\n")
+}
+
+func (change Change) MarshalJSON() ([]byte, error) {
+  return json.Marshal(map[string]interface{}{
+    "kind": change.Kind,
+    "path": change.Path,
+    "old": jsonValue(change.Old),
+    "new": jsonValue(change.New),
+  })
+}
+
+func diffValues(path string, before interface{}, after interface{}, secrets []string, changes *Changes) {
+  beforeMap, beforeIsMap := toMap(before)
+  afterMap, afterIsMap := toMap(after)
+
+  if beforeIsMap && afterIsMap {
+    keys := []string{}
+    beforeKeys := map[string]interface{}{}
+    afterKeys := map[string]interface{}{}
+
+    for k := range beforeMap {
+      beforeKeys[fmt.Sprintf("%v", k)] = k
+      keys = append(keys, fmt.Sprintf("%v", k))
+    }
+
+    for k := range afterMap {
+      afterKeys[fmt.Sprintf("%v", k)] = k
+
+      if _, found := beforeKeys[fmt.Sprintf("%v", k)]; found == false {
+        keys = append(keys, fmt.Sprintf("%v", k))
+      }
+    }
+
+    sort.Strings(keys)
+
+    for _, key := range keys {
+      keyPath := joinPath(path, key)
+      beforeKey, inBefore := beforeKeys[key]
+      afterKey, inAfter := afterKeys[key]
+
+      switch {
+        case inBefore == false:
+          *changes = append(*changes, Change{Kind: Added, Path: keyPath, New: redactValue(keyPath, afterMap[afterKey], secrets)})
+        case inAfter == false:
+          *changes = append(*changes, Change{Kind: Removed, Path: keyPath, Old: redactValue(keyPath, beforeMap[beforeKey], secrets)})
+        default:
+          diffValues(keyPath, beforeMap[beforeKey], afterMap[afterKey], secrets, changes)
+      }
+    }
+
+    return
+  }
+
+  beforeList, beforeIsList := before.([]interface{})
+  afterList, afterIsList := after.([]interface{})
+
+  if beforeIsList && afterIsList {
+    for i := 0; i < len(beforeList) || i < len(afterList); i++ {
+      itemPath := joinPath(path, fmt.Sprintf("%d", i))
+
+      switch {
+        case i >= len(beforeList):
+          *changes = append(*changes, Change{Kind: Added, Path: itemPath, New: redactValue(itemPath, afterList[i], secrets)})
+        case i >= len(afterList):
+          *changes = append(*changes, Change{Kind: Removed, Path: itemPath, Old: redactValue(itemPath, beforeList[i], secrets)})
+        default:
+          diffValues(itemPath, beforeList[i], afterList[i], secrets, changes)
+      }
+    }
+
+    return
+  }
+
+  if reflect.DeepEqual(before, after) == false {
+    *changes = append(*changes, Change{Kind: Changed, Path: path, Old: redactValue(path, before, secrets), New: redactValue(path, after, secrets)})
+  }
+}
+
+// Values are written the way JSON writes them, so strings are quoted
+//  and maps and lists fit on one line
+func renderedValue(value interface{}) string {
+  contents, err := json.Marshal(jsonValue(value))
+
+  if err != nil {
+    return stringify(value)
+  }
+
+  return string(contents)
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+  "os"
+  "fmt"
+  "strings"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+type dotenvEntry struct {
+  name string
+  value string
+  line int
+}
+
+// Keys are normalized the way MergeWithEnvVars normalizes env var names,
+//  so DB__HOST in a .env file ends up under db.host
+func decodeDotenv(contents []byte) (ConfigData, error) {
+  entries, err := parseDotenv(string(contents))
+
+  if err != nil {
+    return nil, err
+  }
+
+  data := ConfigData{}
+
+  for _, entry := range entries {
+    path := envVarPath(entry.name)
+
+    if len(path) > 0 {
+      data.setPath(path, entry.value)
+    }
+  }
+
+  return data, nil
+}
+
+// Sets the variables from a .env file in the process environment.
+//  Variables that are already set are only replaced if overwrite is true.
+//  Loading a .env file with Load never touches the environment.
+func ExportDotenv(path string, overwrite bool) *errtrace.Error {
+  contents, err := readFile(path)
+
+  if err != nil {
+    return err
+  }
+
+  entries, parseErr := parseDotenv(string(contents))
+
+  if parseErr != nil {
+    return errtrace.Wrap(parseErr)
+  }
+
+  for _, entry := range entries {
+    _, exists := os.LookupEnv(entry.name)
+
+    if exists && overwrite == false {
+      continue
+    }
+
+    setErr := os.Setenv(entry.name, entry.value)
+
+    if setErr != nil {
+      return errtrace.Wrap(setErr)
+    }
+  }
+
+  return nil
+}
+
+func ExportDotenvP(path string, overwrite bool) {
+  err := ExportDotenv(path, overwrite)
+
+  if err != nil {
+    panic(err)
+  }
+}
+
+// Supports comments, an optional export prefix, unquoted values (which end
+//  at a " #" comment), single quoted values taken literally and double quoted
+//  values with \n, \r, \t, \", \\ and \$ escapes. Quoted values can span lines.
+func parseDotenv(contents string) ([]dotenvEntry, error) {
+  contents = strings.Replace(contents, "\r\n", "\n", -1)
+  lines := strings.Split(contents, "\n")
+  entries := []dotenvEntry{}
+
+  for i := 0; i < len(lines); i++ {
+    lineNumber := i + 1
+    // Only the leading side is trimmed, quoted values keep trailing spaces
+    line := strings.TrimLeft(lines[i], " \t")
+
+    if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+      continue
+    }
+
+    if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+      line = strings.TrimLeft(line[len("export"):], " \t")
+    }
+
+    separator := strings.Index(line, "=")
+
+    if separator < 0 {
+      return nil, fmt.Errorf("Could not parse dotenv line %d: expected NAME=value", lineNumber)
+    }
+
+    name := strings.TrimSpace(line[:separator])
+
+    if isValidEnvName(name) == false {
+      return nil, fmt.Errorf("Could not parse dotenv line %d: invalid name %q", lineNumber, name)
+    }
+
+    rest := strings.TrimLeft(line[separator+1:], " \t")
+
+    if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+      entries = append(entries, dotenvEntry{name: name, value: unquotedValue(rest), line: lineNumber})
+      continue
+    }
+
+    // Quoted values may continue on the following lines
+    quote := rest[0]
+    raw := rest[1:]
+    value, remainder, closed := quotedValue(raw, quote)
+
+    for closed == false && i + 1 < len(lines) {
+      i++
+      raw = raw + "\n" + lines[i]
+      value, remainder, closed = quotedValue(raw, quote)
+    }
+
+    if closed == false {
+      return nil, fmt.Errorf("Could not parse dotenv line %d: unterminated quoted value", lineNumber)
+    }
+
+    remainder = strings.TrimSpace(remainder)
+
+    if remainder != "" && strings.HasPrefix(remainder, "#") == false {
+      return nil, fmt.Errorf("Could not parse dotenv line %d: unexpected %q after quoted value", lineNumber, remainder)
+    }
+
+    entries = append(entries, dotenvEntry{name: name, value: value, line: lineNumber})
+  }
+
+  return entries, nil
+}
+
+func unquotedValue(raw string) string {
+  if strings.HasPrefix(raw, "#") {
+    return ""
+  }
+
+  for _, marker := range []string{" #", "\t#"} {
+    if i := strings.Index(raw, marker); i >= 0 {
+      raw = raw[:i]
+    }
+  }
+
+  return strings.TrimSpace(raw)
+}
+
+// Returns the value, whatever follows the closing quote and whether
+//  the closing quote was found at all
+func quotedValue(raw string, quote byte) (string, string, bool) {
+  value := []byte{}
+
+  for i := 0; i < len(raw); i++ {
+    c := raw[i]
+
+    if c == quote {
+      return string(value), raw[i+1:], true
+    }
+
+    if c == '\\' && quote == '"' && i + 1 < len(raw) {
+      i++
+
+      switch raw[i] {
+        case 'n':
+          value = append(value, '\n')
+        case 'r':
+          value = append(value, '\r')
+        case 't':
+          value = append(value, '\t')
+        case '"', '\\', '$':
+          value = append(value, raw[i])
+        default:
+          value = append(value, '\\', raw[i])
+      }
+
+      continue
+    }
+
+    value = append(value, c)
+  }
+
+  return string(value), "", false
+}
+
+func isValidEnvName(name string) bool {
+  if name == "" {
+    return false
+  }
+
+  for i, c := range name {
+    isLetter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+    isOther := (c >= '0' && c <= '9') || c == '.' || c == '-'
+
+    if isLetter == false && (isOther == false || i == 0) {
+      return false
+    }
+  }
+
+  return true
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+  "fmt"
+  "path"
+  "strings"
+  "encoding/json"
+  "gopkg.in/yaml.v2"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+// What secret values are replaced with in dumps
+const Redacted = "******"
+
+// Keys whose name matches any of these patterns (see path.Match) are treated
+//  as secrets wherever they are nested. Matching ignores case.
+var SecretPatterns = []string{"*password*", "*token*", "*secret*"}
+
+// Marks keys (dotted paths) whose values must never show up in a dump,
+//  on top of the ones matching SecretPatterns. Marked keys stay marked
+//  in configs merged or derived from this one.
+func (c *Config) MarkSecret(keys ...string) {
+  c.mutex.Lock()
+  defer c.mutex.Unlock()
+
+  for _, key := range keys {
+    if containsString(c.secrets, strings.ToLower(key)) == false {
+      c.secrets = append(c.secrets, strings.ToLower(key))
+    }
+  }
+}
+
+// Tells whether the value under key is redacted in dumps
+func (c *Config) IsSecret(key string) bool {
+  return isSecret(c.secretKeys(), key)
+}
+
+// Serializes the config, defaults included, with secret values replaced
+//  by Redacted. Supports YAML and JSON.
+func (c *Config) Dump(format Format) ([]byte, *errtrace.Error) {
+  data := redactData(c.effectiveData(), c.secretKeys())
+
+  switch format {
+    case YAML:
+      contents, err := yaml.Marshal(data)
+
+      if err != nil {
+        return nil, errtrace.Wrap(err)
+      }
+
+      return contents, nil
+    case JSON:
+      contents, err := json.MarshalIndent(jsonValue(data), "", "  ")
+
+      if err != nil {
+        return nil, errtrace.Wrap(err)
+      }
+
+      return append(contents, '\n'), nil
+    default:
+      return nil, errtrace.New(fmt.Sprintf("Could not dump config: unsupported format %s", format))
+  }
+}
+
+func (c *Config) DumpP(format Format) []byte {
+  contents, err := c.Dump(format)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return contents
+}
+
+// Redacted yaml, so that a config can be logged or printed with %v
+func (c *Config) String() string {
+  contents, err := c.Dump(YAML)
+
+  if err != nil {
+    return err.Error()
+  }
+
+  return string(contents)
+}
+
+// Redacted as well, so that %#v does not leak Data either
+func (c *Config) GoString() string {
+  contents, err := json.Marshal(jsonValue(redactData(c.effectiveData(), c.secretKeys())))
+
+  if err != nil {
+    return err.Error()
+  }
+
+  return fmt.Sprintf("&config.Config{Data: %s}", contents)
+}
+
+func (c *Config) secretKeys() []string {
+  c.mutex.RLock()
+  defer c.mutex.RUnlock()
+
+  return append([]string{}, c.secrets...)
+}
+
+func isSecret(secrets []string, key string) bool {
+  key = strings.ToLower(key)
+
+  for _, secret := range secrets {
+    if key == secret || strings.HasPrefix(key, secret + ".") {
+      return true
+    }
+  }
+
+  for _, segment := range strings.Split(key, ".") {
+    if isSecretName(segment) {
+      return true
+    }
+  }
+
+  return false
+}
+
+func isSecretName(name string) bool {
+  name = strings.ToLower(name)
+
+  for _, pattern := range SecretPatterns {
+    if matched, _ := path.Match(strings.ToLower(pattern), name); matched {
+      return true
+    }
+  }
+
+  return false
+}
+
+func redactData(data ConfigData, secrets []string) ConfigData {
+  result := make(ConfigData, len(data))
+
+  for k, v := range data {
+    result[k] = redactValue(k, v, secrets)
+  }
+
+  return result
+}
+
+// Whole sections under a secret key are replaced, nils are kept to show
+//  that a secret is missing
+func redactValue(key string, value interface{}, secrets []string) interface{} {
+  if value == nil {
+    return nil
+  }
+
+  if isSecret(secrets, key) {
+    return Redacted
+  }
+
+  if m, isMap := toMap(value); isMap {
+    result := make(map[interface{}]interface{}, len(m))
+
+    for k, v := range m {
+      result[k] = redactValue(joinPath(key, fmt.Sprintf("%v", k)), v, secrets)
+    }
+
+    return result
+  }
+
+  if list, isList := value.([]interface{}); isList {
+    result := make([]interface{}, len(list))
+
+    for i, item := range list {
+      result[i] = redactValue(joinPath(key, fmt.Sprintf("%d", i)), item, secrets)
+    }
+
+    return result
+  }
+
+  return value
+}
+
+// encoding/json only takes maps with string keys
+func jsonValue(value interface{}) interface{} {
+  if m, isMap := toMap(value); isMap {
+    result := make(map[string]interface{}, len(m))
+
+    for k, v := range m {
+      result[fmt.Sprintf("%v", k)] = jsonValue(v)
+    }
+
+    return result
+  }
+
+  if list, isList := value.([]interface{}); isList {
+    result := make([]interface{}, len(list))
+
+    for i, item := range list {
+      result[i] = jsonValue(item)
+    }
+
+    return result
+  }
+
+  return value
+}
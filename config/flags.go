@@ -0,0 +1,38 @@
+package config
+
+import (
+  "flag"
+  "strings"
+)
+
+// Turns the flags that were passed on the command line into a config that can
+//  be merged on top of everything else. Flags that were not passed are left
+//  out, so their defaults never replace values from files or env vars.
+//  Dots in flag names reach into nested maps, so --db.port sets db.port.
+//  Call it after fs.Parse.
+func FromFlags(fs *flag.FlagSet) *Config {
+  data := ConfigData{}
+  origins := map[string][]Origin{}
+
+  fs.Visit(func(f *flag.Flag) {
+    path := strings.Split(strings.ToLower(f.Name), ".")
+    value := flagValue(f)
+
+    data.setPath(path, value)
+    addOrigins(origins, strings.Join(path, "."), value, constantOrigin(Origin{Kind: FlagOrigin, Name: f.Name}))
+  })
+
+  return &Config{Data: data, origins: origins}
+}
+
+// Typed values are kept for flags whose value implements flag.Getter,
+//  which all the flags from the standard library do
+func flagValue(f *flag.Flag) interface{} {
+  getter, isGetter := f.Value.(flag.Getter)
+
+  if isGetter {
+    return getter.Get()
+  }
+
+  return f.Value.String()
+}
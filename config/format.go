@@ -0,0 +1,190 @@
+package config
+
+import (
+  "fmt"
+  "bytes"
+  "strings"
+  "encoding/json"
+  "github.com/BurntSushi/toml"
+  "path/filepath"
+  "gopkg.in/yaml.v2"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+type Format string
+
+const (
+  YAML Format = "yaml"
+  JSON Format = "json"
+  TOML Format = "toml"
+  Dotenv Format = "dotenv"
+)
+
+// Files with other extensions are read as yaml, except for .env.local,
+//  .env.production and the like, which are read as dotenv
+var formatsByExtension = map[string]Format{
+  ".yaml": YAML,
+  ".yml": YAML,
+  ".json": JSON,
+  ".toml": TOML,
+  ".env": Dotenv,
+}
+
+// Every decoder returns nested maps as map[interface{}]interface{}, the way
+//  yaml does, so that Merge and SubSection treat all formats the same
+var decoders = map[Format]func([]byte) (ConfigData, error){
+  YAML: decodeYaml,
+  JSON: decodeJson,
+  TOML: decodeToml,
+  Dotenv: decodeDotenv,
+}
+
+func formatFromPath(path string) Format {
+  format, found := formatsByExtension[strings.ToLower(filepath.Ext(path))]
+
+  if found {
+    return format
+  }
+
+  if strings.HasPrefix(strings.ToLower(filepath.Base(path)), ".env.") {
+    return Dotenv
+  }
+
+  return YAML
+}
+
+func decodeConfigData(contents []byte, format Format) (ConfigData, *errtrace.Error) {
+  decoder, found := decoders[format]
+
+  if found == false {
+    return nil, errtrace.New(fmt.Sprintf("Unsupported config format: %s", format))
+  }
+
+  configData, err := decoder(contents)
+
+  if err != nil {
+    return nil, errtrace.Wrap(err)
+  }
+
+  return configData, nil
+}
+
+func decodeYaml(contents []byte) (ConfigData, error) {
+  configData := ConfigData{}
+  err := yaml.Unmarshal(contents, &configData)
+
+  return configData, err
+}
+
+// Numbers come out as int when they are whole, as they would from yaml
+func decodeJson(contents []byte) (ConfigData, error) {
+  if len(bytes.TrimSpace(contents)) == 0 {
+    return ConfigData{}, nil
+  }
+
+  decoder := json.NewDecoder(bytes.NewReader(contents))
+  decoder.UseNumber()
+
+  data := map[string]interface{}{}
+  err := decoder.Decode(&data)
+
+  if err != nil {
+    return nil, err
+  }
+
+  configData := make(ConfigData, len(data))
+
+  for k, v := range data {
+    configData[k] = normalizeJson(v)
+  }
+
+  return configData, nil
+}
+
+func normalizeJson(value interface{}) interface{} {
+  switch v := value.(type) {
+    case map[string]interface{}:
+      result := make(map[interface{}]interface{}, len(v))
+
+      for k, item := range v {
+        result[k] = normalizeJson(item)
+      }
+
+      return result
+    case []interface{}:
+      result := make([]interface{}, len(v))
+
+      for i, item := range v {
+        result[i] = normalizeJson(item)
+      }
+
+      return result
+    case json.Number:
+      if i, err := v.Int64(); err == nil && int64(int(i)) == i {
+        return int(i)
+      }
+
+      if f, err := v.Float64(); err == nil {
+        return f
+      }
+
+      return v.String()
+    default:
+      return v
+  }
+}
+
+// Tables become nested maps, integers int and datetimes time.Time
+func decodeToml(contents []byte) (ConfigData, error) {
+  data := map[string]interface{}{}
+  _, err := toml.Decode(string(contents), &data)
+
+  if err != nil {
+    return nil, err
+  }
+
+  configData := make(ConfigData, len(data))
+
+  for k, v := range data {
+    configData[k] = normalizeToml(v)
+  }
+
+  return configData, nil
+}
+
+func normalizeToml(value interface{}) interface{} {
+  switch v := value.(type) {
+    case map[string]interface{}:
+      result := make(map[interface{}]interface{}, len(v))
+
+      for k, item := range v {
+        result[k] = normalizeToml(item)
+      }
+
+      return result
+    case []map[string]interface{}:
+      result := make([]interface{}, len(v))
+
+      for i, item := range v {
+        result[i] = normalizeToml(item)
+      }
+
+      return result
+    case []interface{}:
+      result := make([]interface{}, len(v))
+
+      for i, item := range v {
+        result[i] = normalizeToml(item)
+      }
+
+      return result
+    case int64:
+      if int64(int(v)) == v {
+        return int(v)
+      }
+
+      return v
+    default:
+      return v
+  }
+}
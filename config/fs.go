@@ -0,0 +1,49 @@
+package config
+
+import (
+  "io/fs"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+// Loads from any fs.FS, so embed.FS, os.DirFS and fstest.MapFS all work
+//  without packr. Paths use forward slashes, as fs.FS requires.
+//  The format is picked from the file extension.
+func LoadFS(fsys fs.FS, path string) (*Config, *errtrace.Error) {
+  contents, err := fs.ReadFile(fsys, path)
+
+  if err != nil {
+    return nil, errtrace.Wrap(err)
+  }
+
+  return loadConfig(path, contents, "", formatFromPath(path))
+}
+
+func LoadFSP(fsys fs.FS, path string) *Config {
+  c, err := LoadFS(fsys, path)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
+
+func LoadSectionFS(fsys fs.FS, path string, section string) (*Config, *errtrace.Error) {
+  contents, err := fs.ReadFile(fsys, path)
+
+  if err != nil {
+    return nil, errtrace.Wrap(err)
+  }
+
+  return loadConfig(path, contents, section, formatFromPath(path))
+}
+
+func LoadSectionFSP(fsys fs.FS, path string, section string) *Config {
+  c, err := LoadSectionFS(fsys, path, section)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
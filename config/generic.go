@@ -0,0 +1,121 @@
+package config
+
+import (
+  "fmt"
+  "net"
+  "sync"
+  "time"
+  "reflect"
+  "strings"
+  "net/url"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+var convertersMutex sync.RWMutex
+var converters = map[reflect.Type]func(interface{}) (interface{}, error){}
+
+// Types the typed getters already know how to read
+var builtinGetters = map[reflect.Type]func(*Config, string) (interface{}, *errtrace.Error){
+  reflect.TypeOf(""): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetString(key) },
+  reflect.TypeOf(0): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetInt(key) },
+  reflect.TypeOf(0.0): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetFloat(key) },
+  reflect.TypeOf(false): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetBool(key) },
+  reflect.TypeOf(time.Duration(0)): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetDuration(key) },
+  reflect.TypeOf(time.Time{}): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetTime(key) },
+  reflect.TypeOf(&url.URL{}): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetURL(key) },
+  reflect.TypeOf(net.IP{}): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetIP(key) },
+  reflect.TypeOf(&net.IPNet{}): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetIPNet(key) },
+  reflect.TypeOf([]string{}): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetStringSlice(key) },
+  reflect.TypeOf([]int{}): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetIntSlice(key) },
+  reflect.TypeOf([]float64{}): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetFloatSlice(key) },
+  reflect.TypeOf(map[string]interface{}{}): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetStringMap(key) },
+  reflect.TypeOf(map[string]string{}): func(c *Config, key string) (interface{}, *errtrace.Error) { return c.GetStringMapString(key) },
+}
+
+// Teaches Get, MustGet and Unmarshal to read values of type T. The converter
+//  gets the value as it is in the config, a string when it comes from an env
+//  var. Its errors are reported with the key, like the ones of the built-in
+//  getters. Registering a type again replaces its converter.
+//
+//    config.RegisterConverter(func(value interface{}) (Level, error) {
+//      return ParseLevel(fmt.Sprintf("%v", value))
+//    })
+func RegisterConverter[T any](convert func(value interface{}) (T, error)) {
+  convertersMutex.Lock()
+  defer convertersMutex.Unlock()
+
+  converters[reflect.TypeOf((*T)(nil)).Elem()] = func(value interface{}) (interface{}, error) {
+    return convert(value)
+  }
+}
+
+// Reads the value under key as T. Registered converters are used first,
+//  then the typed getters like GetDuration or GetStringSlice for the types
+//  they return, and then the conversions Unmarshal does, so structs, maps,
+//  slices and encoding.TextUnmarshaler types work too.
+func Get[T any](c *Config, key string) (T, *errtrace.Error) {
+  var result T
+  targetType := reflect.TypeOf((*T)(nil)).Elem()
+
+  if convert, found := converterFor(targetType); found {
+    value, err := c.Get(key)
+
+    if err != nil {
+      return result, err
+    }
+
+    converted, convertErr := convert(value)
+
+    if convertErr != nil {
+      return result, conversionError(key, value, targetType.String(), convertErr.Error())
+    }
+
+    result, _ = converted.(T)
+
+    return result, nil
+  }
+
+  if getter, found := builtinGetters[targetType]; found {
+    value, err := getter(c, key)
+
+    if err != nil {
+      return result, err
+    }
+
+    return value.(T), nil
+  }
+
+  value, err := c.Get(key)
+
+  if err != nil {
+    return result, err
+  }
+
+  d := decoder{encoded: c.encodesCollections}
+  d.decode(key, value, reflect.ValueOf(&result).Elem())
+
+  if len(d.failures) > 0 {
+    return result, errtrace.New(fmt.Sprintf("Could not read key: %s (%s)", key, strings.Join(d.failures, ", ")))
+  }
+
+  return result, nil
+}
+
+func MustGet[T any](c *Config, key string) T {
+  value, err := Get[T](c, key)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return value
+}
+
+func converterFor(t reflect.Type) (func(interface{}) (interface{}, error), bool) {
+  convertersMutex.RLock()
+  defer convertersMutex.RUnlock()
+
+  convert, found := converters[t]
+
+  return convert, found
+}
@@ -0,0 +1,463 @@
+package config
+
+import (
+  "fmt"
+  "net"
+  "math"
+  "time"
+  "strconv"
+  "strings"
+  "net/url"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+// Multipliers of the units GetByteSize understands, matched ignoring case.
+//  KB, MB and the like are powers of 1000, KiB, MiB and the like powers of 1024.
+var byteSizeUnits = map[string]float64{
+  "": 1,
+  "b": 1,
+  "k": 1e3, "kb": 1e3, "ki": 1 << 10, "kib": 1 << 10,
+  "m": 1e6, "mb": 1e6, "mi": 1 << 20, "mib": 1 << 20,
+  "g": 1e9, "gb": 1e9, "gi": 1 << 30, "gib": 1 << 30,
+  "t": 1e12, "tb": 1e12, "ti": 1 << 40, "tib": 1 << 40,
+  "p": 1e15, "pb": 1e15, "pi": 1 << 50, "pib": 1 << 50,
+}
+
+func conversionError(key string, value interface{}, target string, reason string) *errtrace.Error {
+  return errtrace.New(fmt.Sprintf("Could not read key: %s (cannot convert %q to %s: %s)", key, stringify(value), target, reason))
+}
+
+// Strings are parsed with time.ParseDuration, so "30s" and "1h30m" work.
+//  Plain numbers are nanoseconds, like in time.Duration itself.
+func (c *Config) GetDuration(key string) (time.Duration, *errtrace.Error) {
+  value, e := c.Get(key)
+
+  if e != nil {
+    return 0, e
+  }
+
+  // Native numbers are taken as they are, stringify would write large
+  //  floats like 1.5e+09 which neither parser below reads
+  switch v := value.(type) {
+    case time.Duration:
+      return v, nil
+    case int:
+      return time.Duration(v), nil
+    case int64:
+      return time.Duration(v), nil
+    case float64:
+      if v < math.MinInt64 || v >= math.MaxInt64 {
+        return 0, conversionError(key, value, "a duration", "too large")
+      }
+
+      return time.Duration(v), nil
+  }
+
+  s := strings.TrimSpace(stringify(value))
+
+  if nanoseconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+    return time.Duration(nanoseconds), nil
+  }
+
+  duration, err := time.ParseDuration(s)
+
+  if err != nil {
+    return 0, conversionError(key, value, "a duration", "expected a number with a unit like 30s")
+  }
+
+  return duration, nil
+}
+
+func (c *Config) GetDurationP(key string) time.Duration {
+  value, e := c.GetDuration(key)
+
+  if e != nil {
+    panic(e)
+  }
+
+  return value
+}
+
+// Takes TOML datetimes as they are and parses strings as RFC 3339,
+//  with or without fractional seconds, or as plain dates like 2006-01-02
+func (c *Config) GetTime(key string) (time.Time, *errtrace.Error) {
+  value, e := c.Get(key)
+
+  if e != nil {
+    return time.Time{}, e
+  }
+
+  if t, isTime := value.(time.Time); isTime {
+    return t, nil
+  }
+
+  for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+    t, err := time.Parse(layout, stringify(value))
+
+    if err == nil {
+      return t, nil
+    }
+  }
+
+  return time.Time{}, conversionError(key, value, "a time", "expected RFC 3339 like 2006-01-02T15:04:05Z07:00")
+}
+
+func (c *Config) GetTimeP(key string) time.Time {
+  value, e := c.GetTime(key)
+
+  if e != nil {
+    panic(e)
+  }
+
+  return value
+}
+
+// Returns a number of bytes. Strings can have a unit, as in 512MiB, 1.5GB
+//  or 64k, see byteSizeUnits. Plain numbers are bytes.
+func (c *Config) GetByteSize(key string) (int64, *errtrace.Error) {
+  value, e := c.Get(key)
+
+  if e != nil {
+    return 0, e
+  }
+
+  var size float64
+
+  // Native numbers are bytes, only strings have units
+  switch v := value.(type) {
+    case int:
+      if v >= 0 {
+        return int64(v), nil
+      }
+
+      size = float64(v)
+    case int64:
+      if v >= 0 {
+        return v, nil
+      }
+
+      size = float64(v)
+    case float64:
+      size = v
+    default:
+      s := strings.TrimSpace(stringify(value))
+      split := strings.IndexFunc(s, func(r rune) bool {
+        return (r < '0' || r > '9') && r != '.'
+      })
+
+      if split < 0 {
+        split = len(s)
+      }
+
+      number, err := strconv.ParseFloat(s[:split], 64)
+
+      if err != nil {
+        return 0, conversionError(key, value, "a byte size", "expected a positive number with an optional unit like 512MiB")
+      }
+
+      multiplier, found := byteSizeUnits[strings.ToLower(strings.TrimSpace(s[split:]))]
+
+      if found == false {
+        return 0, conversionError(key, value, "a byte size", fmt.Sprintf("unknown unit %q", strings.TrimSpace(s[split:])))
+      }
+
+      size = number * multiplier
+  }
+
+  if size < 0 {
+    return 0, conversionError(key, value, "a byte size", "expected a positive number with an optional unit like 512MiB")
+  }
+
+  if size >= math.MaxInt64 {
+    return 0, conversionError(key, value, "a byte size", "too large")
+  }
+
+  return int64(size), nil
+}
+
+func (c *Config) GetByteSizeP(key string) int64 {
+  value, e := c.GetByteSize(key)
+
+  if e != nil {
+    panic(e)
+  }
+
+  return value
+}
+
+// Only absolute urls, the ones with a scheme, are accepted
+func (c *Config) GetURL(key string) (*url.URL, *errtrace.Error) {
+  value, e := c.GetString(key)
+
+  if e != nil {
+    return nil, e
+  }
+
+  u, err := url.Parse(value)
+
+  if err != nil {
+    return nil, conversionError(key, value, "a url", err.Error())
+  }
+
+  if u.Scheme == "" {
+    return nil, conversionError(key, value, "a url", "expected an absolute url")
+  }
+
+  return u, nil
+}
+
+func (c *Config) GetURLP(key string) *url.URL {
+  value, e := c.GetURL(key)
+
+  if e != nil {
+    panic(e)
+  }
+
+  return value
+}
+
+func (c *Config) GetIP(key string) (net.IP, *errtrace.Error) {
+  value, e := c.GetString(key)
+
+  if e != nil {
+    return nil, e
+  }
+
+  ip := net.ParseIP(strings.TrimSpace(value))
+
+  if ip == nil {
+    return nil, conversionError(key, value, "an ip", "expected an IPv4 or IPv6 address")
+  }
+
+  return ip, nil
+}
+
+func (c *Config) GetIPP(key string) net.IP {
+  value, e := c.GetIP(key)
+
+  if e != nil {
+    panic(e)
+  }
+
+  return value
+}
+
+// Reads CIDR notation like 10.0.0.0/8, the address is masked to the network
+func (c *Config) GetIPNet(key string) (*net.IPNet, *errtrace.Error) {
+  value, e := c.GetString(key)
+
+  if e != nil {
+    return nil, e
+  }
+
+  _, network, err := net.ParseCIDR(strings.TrimSpace(value))
+
+  if err != nil {
+    return nil, conversionError(key, value, "a network", "expected CIDR notation like 10.0.0.0/8")
+  }
+
+  return network, nil
+}
+
+func (c *Config) GetIPNetP(key string) *net.IPNet {
+  value, e := c.GetIPNet(key)
+
+  if e != nil {
+    panic(e)
+  }
+
+  return value
+}
+
+// Lists can also be comma separated or JSON arrays, as they come from env
+//  vars. Items that are not strings are formatted like GetString does.
+func (c *Config) GetStringSlice(key string) ([]string, *errtrace.Error) {
+  items, e := c.listItems(key)
+
+  if e != nil {
+    return nil, e
+  }
+
+  result := make([]string, len(items))
+
+  for i, item := range items {
+    result[i] = stringify(item)
+  }
+
+  return result, nil
+}
+
+func (c *Config) GetStringSliceP(key string) []string {
+  value, e := c.GetStringSlice(key)
+
+  if e != nil {
+    panic(e)
+  }
+
+  return value
+}
+
+func (c *Config) GetIntSlice(key string) ([]int, *errtrace.Error) {
+  items, e := c.listItems(key)
+
+  if e != nil {
+    return nil, e
+  }
+
+  result := make([]int, len(items))
+
+  for i, item := range items {
+    value, err := strconv.Atoi(strings.TrimSpace(stringify(item)))
+
+    if err != nil {
+      return nil, conversionError(joinPath(key, strconv.Itoa(i)), item, "an int", "expected a whole number")
+    }
+
+    result[i] = value
+  }
+
+  return result, nil
+}
+
+func (c *Config) GetIntSliceP(key string) []int {
+  value, e := c.GetIntSlice(key)
+
+  if e != nil {
+    panic(e)
+  }
+
+  return value
+}
+
+func (c *Config) GetFloatSlice(key string) ([]float64, *errtrace.Error) {
+  items, e := c.listItems(key)
+
+  if e != nil {
+    return nil, e
+  }
+
+  result := make([]float64, len(items))
+
+  for i, item := range items {
+    value, err := strconv.ParseFloat(strings.TrimSpace(stringify(item)), 64)
+
+    if err != nil {
+      return nil, conversionError(joinPath(key, strconv.Itoa(i)), item, "a float", "expected a number")
+    }
+
+    result[i] = value
+  }
+
+  return result, nil
+}
+
+func (c *Config) GetFloatSliceP(key string) []float64 {
+  value, e := c.GetFloatSlice(key)
+
+  if e != nil {
+    panic(e)
+  }
+
+  return value
+}
+
+// Returns a section with string keys. Values are copies, so the map can be
+//  modified. Strings from env vars can be JSON objects or key=value pairs
+//  separated by commas.
+func (c *Config) GetStringMap(key string) (map[string]interface{}, *errtrace.Error) {
+  m, e := c.mapItems(key)
+
+  if e != nil {
+    return nil, e
+  }
+
+  result := make(map[string]interface{}, len(m))
+
+  for k, v := range m {
+    result[fmt.Sprintf("%v", k)] = deepCopy(v)
+  }
+
+  return result, nil
+}
+
+func (c *Config) GetStringMapP(key string) map[string]interface{} {
+  value, e := c.GetStringMap(key)
+
+  if e != nil {
+    panic(e)
+  }
+
+  return value
+}
+
+// Same as GetStringMap but the values are formatted like GetString does.
+//  Nested maps and lists are an error.
+func (c *Config) GetStringMapString(key string) (map[string]string, *errtrace.Error) {
+  m, e := c.mapItems(key)
+
+  if e != nil {
+    return nil, e
+  }
+
+  result := make(map[string]string, len(m))
+
+  for k, v := range m {
+    if isScalar(v) == false {
+      return nil, errtrace.New(fmt.Sprintf("Could not read key: %s (cannot convert a map or a list to a string)", joinPath(key, fmt.Sprintf("%v", k))))
+    }
+
+    result[fmt.Sprintf("%v", k)] = stringify(v)
+  }
+
+  return result, nil
+}
+
+func (c *Config) GetStringMapStringP(key string) map[string]string {
+  value, e := c.GetStringMapString(key)
+
+  if e != nil {
+    panic(e)
+  }
+
+  return value
+}
+
+func (c *Config) listItems(key string) ([]interface{}, *errtrace.Error) {
+  value, e := c.Get(key)
+
+  if e != nil {
+    return nil, e
+  }
+
+  if value == nil {
+    return []interface{}{}, nil
+  }
+
+  items, isList := sliceItems(value, c.encodesCollections(key))
+
+  if isList == false {
+    return nil, conversionError(key, value, "a list", "expected a list, or a comma separated string or a JSON array from an env var or a flag")
+  }
+
+  return items, nil
+}
+
+func (c *Config) mapItems(key string) (map[interface{}]interface{}, *errtrace.Error) {
+  value, e := c.Get(key)
+
+  if e != nil {
+    return nil, e
+  }
+
+  if value == nil {
+    return map[interface{}]interface{}{}, nil
+  }
+
+  m, isMap := mapItems(value, c.encodesCollections(key))
+
+  if isMap == false {
+    return nil, conversionError(key, value, "a map", "expected a map, or a JSON object or key=value pairs separated by commas from an env var or a flag")
+  }
+
+  return m, nil
+}
@@ -0,0 +1,278 @@
+package config
+
+import (
+  "os"
+  "fmt"
+  "strings"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+// Resolves ${NAME} references in the string values that were loaded from
+//  files and returns the result as a new config. NAME is looked up in the
+//  environment first and then as a key (or dotted path) of the config,
+//  defaults included.
+//
+//  ${NAME}            empty when NAME is not set
+//  ${NAME:-default}   default when NAME is not set or empty
+//  ${NAME:?message}   fails with message when NAME is not set or empty
+//  $$                 a literal $
+//
+//  A value that is nothing but a reference to a key keeps the type of
+//  that key, so port: ${db.port} stays an int. Values that came from dotenv
+//  files, env vars, flags, --set, Set or the defaults are taken literally,
+//  as are values written to Data directly. Dotenv files have quoting rules
+//  of their own, interpolating them again would undo those.
+func (c *Config) Interpolate() (*Config, *errtrace.Error) {
+  data, defaults := c.snapshot()
+  origins := c.originsCopy()
+
+  literal := func(path string) bool {
+    origin, found := currentOrigin(origins, path)
+
+    return found == false || origin.Kind != FileOrigin || origin.Format == Dotenv
+  }
+
+  result, err := interpolateData(data, mergeData(defaults, data), literal)
+
+  if err != nil {
+    return nil, err
+  }
+
+  return c.derive(result, defaults), nil
+}
+
+func (c *Config) InterpolateP() *Config {
+  result, err := c.Interpolate()
+
+  if err != nil {
+    panic(err)
+  }
+
+  return result
+}
+
+// Interpolates data, references are looked up in lookup. Strings for which
+//  literal returns true are kept as they are.
+func interpolateData(data ConfigData, lookup ConfigData, literal func(string) bool) (ConfigData, *errtrace.Error) {
+  i := interpolator{
+    data: lookup,
+    literal: literal,
+    resolved: map[string]interface{}{},
+    resolving: []string{},
+  }
+
+  result := make(ConfigData, len(data))
+
+  for k, v := range data {
+    value, err := i.value(k, v)
+
+    if err != nil {
+      return nil, err
+    }
+
+    result[k] = value
+  }
+
+  return result, nil
+}
+
+type interpolator struct {
+  data ConfigData
+  literal func(string) bool
+  resolved map[string]interface{}
+  resolving []string
+}
+
+func (i *interpolator) value(path string, value interface{}) (interface{}, *errtrace.Error) {
+  switch v := value.(type) {
+    case string:
+      return i.resolve(path, v)
+    case map[interface{}]interface{}:
+      result := make(map[interface{}]interface{}, len(v))
+
+      for k, item := range v {
+        resolved, err := i.value(joinPath(path, fmt.Sprintf("%v", k)), item)
+
+        if err != nil {
+          return nil, err
+        }
+
+        result[k] = resolved
+      }
+
+      return result, nil
+    case []interface{}:
+      result := make([]interface{}, len(v))
+
+      for index, item := range v {
+        resolved, err := i.value(joinPath(path, fmt.Sprintf("%d", index)), item)
+
+        if err != nil {
+          return nil, err
+        }
+
+        result[index] = resolved
+      }
+
+      return result, nil
+    default:
+      return value, nil
+  }
+}
+
+// Every key is resolved once, following references to other keys
+//  and failing when they lead back to a key that is still being resolved
+func (i *interpolator) resolve(path string, s string) (interface{}, *errtrace.Error) {
+  if i.literal(path) {
+    return s, nil
+  }
+
+  if resolved, found := i.resolved[path]; found {
+    return resolved, nil
+  }
+
+  for index, resolving := range i.resolving {
+    if resolving == path {
+      cycle := append(append([]string{}, i.resolving[index:]...), path)
+      return nil, errtrace.New(fmt.Sprintf("Could not interpolate %s: reference cycle %s", path, strings.Join(cycle, " -> ")))
+    }
+  }
+
+  i.resolving = append(i.resolving, path)
+  resolved, err := i.expand(path, s)
+  i.resolving = i.resolving[:len(i.resolving)-1]
+
+  if err != nil {
+    return nil, err
+  }
+
+  i.resolved[path] = resolved
+
+  return resolved, nil
+}
+
+func (i *interpolator) expand(path string, s string) (interface{}, *errtrace.Error) {
+  if strings.Contains(s, "$") == false {
+    return s, nil
+  }
+
+  result := []byte{}
+
+  for position := 0; position < len(s); position++ {
+    c := s[position]
+
+    if c != '$' || position + 1 == len(s) {
+      result = append(result, c)
+      continue
+    }
+
+    if s[position+1] == '$' {
+      result = append(result, '$')
+      position++
+      continue
+    }
+
+    if s[position+1] != '{' {
+      result = append(result, c)
+      continue
+    }
+
+    end := closingBrace(s, position + 2)
+
+    if end < 0 {
+      return nil, errtrace.New(fmt.Sprintf("Could not interpolate %s: missing } in %q", path, s))
+    }
+
+    value, err := i.reference(path, s[position+2:end])
+
+    if err != nil {
+      return nil, err
+    }
+
+    // Keeps the type of a referenced key when it is the whole value
+    if position == 0 && end == len(s) - 1 {
+      return value, nil
+    }
+
+    result = append(result, stringify(value)...)
+    position = end
+  }
+
+  return string(result), nil
+}
+
+func (i *interpolator) reference(path string, expression string) (interface{}, *errtrace.Error) {
+  name := expression
+  operator := ""
+  argument := ""
+
+  if index := strings.Index(expression, ":"); index >= 0 && index + 1 < len(expression) {
+    switch expression[index+1] {
+      case '-', '?':
+        name = expression[:index]
+        operator = expression[index:index+2]
+        argument = expression[index+2:]
+    }
+  }
+
+  value, found, err := i.lookup(name)
+
+  if err != nil {
+    return nil, err
+  }
+
+  if found && stringify(value) != "" {
+    return value, nil
+  }
+
+  switch operator {
+    case ":-":
+      return i.expand(path, argument)
+    case ":?":
+      message := argument
+
+      if message == "" {
+        message = fmt.Sprintf("%s is not set", name)
+      }
+
+      return nil, errtrace.New(fmt.Sprintf("Could not interpolate %s: %s", path, message))
+  }
+
+  return "", nil
+}
+
+func (i *interpolator) lookup(name string) (interface{}, bool, *errtrace.Error) {
+  if value, found := os.LookupEnv(name); found {
+    return value, true, nil
+  }
+
+  for _, key := range []string{name, strings.ToLower(name)} {
+    value, err := i.data.get(key)
+
+    if err == nil {
+      resolved, resolveErr := i.value(key, value)
+      return resolved, true, resolveErr
+    }
+  }
+
+  return nil, false, nil
+}
+
+func closingBrace(s string, position int) int {
+  depth := 0
+
+  for ; position < len(s); position++ {
+    switch s[position] {
+      case '{':
+        depth++
+      case '}':
+        if depth == 0 {
+          return position
+        }
+
+        depth--
+    }
+  }
+
+  return -1
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+  "bytes"
+  "strconv"
+  "strings"
+  "encoding/json"
+)
+
+// Finds the lines keys are written on, so origins can point at them.
+//  Paths are lowercased. JSON keys are found at any depth with the decoder,
+//  dotenv lines come from the dotenv parser. For yaml only top-level keys
+//  are found. Keys without a line, like nested yaml keys and everything
+//  in TOML files, get origins that only name the file.
+var lineScanners = map[Format]func([]byte) map[string]int{
+  YAML: yamlLines,
+  JSON: jsonLines,
+  Dotenv: dotenvLines,
+}
+
+func keyLines(contents []byte, format Format) map[string]int {
+  scanner, found := lineScanners[format]
+
+  if found == false {
+    return map[string]int{}
+  }
+
+  return scanner(contents)
+}
+
+// Keys that start a line are the top-level keys of the document. Only
+//  the first document is read, like yaml.Unmarshal does.
+func yamlLines(contents []byte) map[string]int {
+  lines := map[string]int{}
+  started := false
+
+  for n, line := range strings.Split(strings.Replace(string(contents), "\r\n", "\n", -1), "\n") {
+    if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "...") {
+      if started {
+        break
+      }
+
+      continue
+    }
+
+    if line == "" || strings.ContainsRune(" \t#-%", rune(line[0])) {
+      continue
+    }
+
+    started = true
+    key, _, isKey := yamlKey(strings.TrimSpace(line))
+
+    if isKey {
+      if _, found := lines[strings.ToLower(key)]; found == false {
+        lines[strings.ToLower(key)] = n + 1
+      }
+    }
+  }
+
+  return lines
+}
+
+// Splits "key: value", the key may be quoted
+func yamlKey(text string) (string, string, bool) {
+  if text == "" || strings.ContainsRune("{[&*!|>", rune(text[0])) {
+    return "", "", false
+  }
+
+  start := 0
+
+  if text[0] == '"' || text[0] == '\'' {
+    closing := strings.IndexByte(text[1:], text[0])
+
+    if closing < 0 {
+      return "", "", false
+    }
+
+    start = closing + 2
+  }
+
+  for i := start; i < len(text); i++ {
+    if text[i] == '#' && i > 0 && text[i-1] == ' ' {
+      break
+    }
+
+    if text[i] == ':' && (i + 1 == len(text) || text[i+1] == ' ') {
+      key := strings.TrimSpace(text[:i])
+
+      if start > 0 {
+        key = key[1:start-1]
+      }
+
+      return key, strings.TrimSpace(text[i+1:]), true
+    }
+  }
+
+  return "", "", false
+}
+
+type jsonLevel struct {
+  path string
+  isObject bool
+  expectKey bool
+  key string
+  index int
+}
+
+// Walks the tokens, the decoder tells how far into the file each one ends
+func jsonLines(contents []byte) map[string]int {
+  lines := map[string]int{}
+  stack := []*jsonLevel{}
+  decoder := json.NewDecoder(bytes.NewReader(contents))
+  decoder.UseNumber()
+  counted := 0
+  line := 1
+
+  lineAt := func(offset int64) int {
+    line += bytes.Count(contents[counted:offset], []byte("\n"))
+    counted = int(offset)
+
+    return line
+  }
+
+  for {
+    token, err := decoder.Token()
+
+    if err != nil {
+      break
+    }
+
+    current := lineAt(decoder.InputOffset())
+
+    if delim, isDelim := token.(json.Delim); isDelim && (delim == '}' || delim == ']') {
+      if len(stack) > 0 {
+        stack = stack[:len(stack)-1]
+      }
+
+      continue
+    }
+
+    path := ""
+
+    if len(stack) > 0 {
+      top := stack[len(stack)-1]
+
+      if top.isObject && top.expectKey {
+        top.key = strings.ToLower(token.(string))
+        top.expectKey = false
+        lines[joinPath(top.path, top.key)] = current
+        continue
+      }
+
+      if top.isObject {
+        path = joinPath(top.path, top.key)
+        top.expectKey = true
+      } else {
+        path = joinPath(top.path, strconv.Itoa(top.index))
+        lines[path] = current
+        top.index++
+      }
+    }
+
+    if delim, isDelim := token.(json.Delim); isDelim {
+      stack = append(stack, &jsonLevel{path: path, isObject: delim == '{', expectKey: true})
+    }
+  }
+
+  return lines
+}
+
+func dotenvLines(contents []byte) map[string]int {
+  lines := map[string]int{}
+  entries, err := parseDotenv(string(contents))
+
+  if err != nil {
+    return lines
+  }
+
+  for _, entry := range entries {
+    lines[strings.Join(envVarPath(entry.name), ".")] = entry.line
+  }
+
+  return lines
+}
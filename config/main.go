@@ -1,30 +1,69 @@
 package config
 
 import (
+  "io"
   "os"
   "fmt"
+  "sort"
+  "sync"
+  "time"
+  "runtime"
   "strconv"
   "strings"
+  "io/ioutil"
   "path/filepath"
-  "gopkg.in/yaml.v2"
   "github.com/renra/go-errtrace/errtrace"
   "github.com/gobuffalo/packr/v2"
 )
 
 type ConfigData map[string]interface{}
 
+// Separates nesting levels in env var names, so DB__POOL__MAX sets db.pool.max.
+//  Set it to an empty string to always merge env vars as top level keys.
+var EnvVarSeparator = "__"
+
+// Safe for concurrent use through its methods. Reading or writing Data
+//  directly bypasses the lock, so only do that before sharing the config.
+//  Maps and slices returned by Get are shared with the config and must
+//  not be modified.
 type Config struct {
   Data ConfigData
+  defaults ConfigData
+  secrets []string
+  origins map[string][]Origin
+  mutex sync.RWMutex
 }
 
+// Values missing in Data are taken from the defaults. When both have a map
+//  under the key, the maps are merged with Data on top.
 func (c *Config) Get(key string) (interface{}, *errtrace.Error) {
-  v, found := c.Data[key]
+  c.mutex.RLock()
+  defer c.mutex.RUnlock()
 
-  if found {
-    return v, nil
-  } else {
-    return v, errtrace.New(fmt.Sprintf("Could not read key: %s", key))
+  v, err := c.Data.get(key)
+
+  if c.defaults == nil {
+    return v, err
+  }
+
+  defaultValue, defaultErr := c.defaults.get(key)
+
+  if defaultErr != nil || (err != nil && c.Data.shadows(key)) {
+    return v, err
+  }
+
+  if err != nil {
+    return defaultValue, nil
+  }
+
+  _, isMap := toMap(v)
+  _, defaultIsMap := toMap(defaultValue)
+
+  if isMap && defaultIsMap {
+    return mergeValues(defaultValue, v), nil
   }
+
+  return v, nil
 }
 
 func (c *Config) GetP(key string) interface{} {
@@ -37,18 +76,33 @@ func (c *Config) GetP(key string) interface{} {
   return v
 }
 
+// Keys with dots are paths like in Get, so Set("db.host", "x") writes
+//  the host key of the db section and keeps its other keys
 func (c *Config) Set(key string, value interface{}) {
-  c.Data[key] = value
+  c.mutex.Lock()
+  defer c.mutex.Unlock()
+
+  if c.Data == nil {
+    c.Data = ConfigData{}
+  }
+
+  c.Data.setPath(strings.Split(key, "."), value)
+
+  if c.origins == nil {
+    c.origins = map[string][]Origin{}
+  }
+
+  // Set replaces the whole value under key, sections are not merged
+  overrideOrigins(c.origins, key, value, true, false)
+
+  _, file, line, _ := runtime.Caller(1)
+  addOrigins(c.origins, key, value, constantOrigin(Origin{Kind: SetCallOrigin, Name: file, Line: line}))
 }
 
 func (c *Config) GetString(key string) (string, *errtrace.Error) {
   value, e := c.Get(key)
 
-  if value == nil {
-    value = ""
-  }
-
-  return fmt.Sprintf("%v", value), e
+  return stringify(value), e
 }
 
 func (c *Config) GetStringP(key string) string {
@@ -127,47 +181,97 @@ func (c *Config) GetBoolP(key string) bool {
   return value
 }
 
+// Nested maps are merged recursively, so that only needs to contain
+//  the keys that differ. Neither config is modified.
+//  Defaults of both configs are merged the same way and stay underneath.
 func (this *Config) Merge(that *Config) *Config {
-  data := ConfigData{}
+  thisData, thisDefaults := this.snapshot()
+  thatData, thatDefaults := that.snapshot()
 
-  for k, v := range this.Data {
-    data[k] = v
+  data := mergeData(thisData, thatData)
+
+  if data == nil {
+    data = ConfigData{}
   }
 
-  for k, v := range that.Data {
-    data[k] = v
+  secrets := this.secretKeys()
+
+  for _, secret := range that.secretKeys() {
+    if containsString(secrets, secret) == false {
+      secrets = append(secrets, secret)
+    }
   }
 
-  return &Config{Data: data}
+  origins := mergeOrigins(this.originsCopy(), that.originsCopy())
+
+  return &Config{Data: data, defaults: mergeData(thisDefaults, thatDefaults), secrets: secrets, origins: origins}
 }
 
 func (c *Config) MergeWithEnvVars() *Config {
-  data := ConfigData{}
+  return c.mergeEnvVars("")
+}
+
+// Only env vars starting with the prefix are merged and the prefix is stripped,
+//  so with prefix MYAPP_ the variable MYAPP_WIDTH ends up under the key width
+func (c *Config) MergeWithPrefixedEnvVars(prefix string) *Config {
+  return c.mergeEnvVars(prefix)
+}
+
+func (c *Config) mergeEnvVars(prefix string) *Config {
+  data, defaults := c.snapshot()
 
-  for k, v := range c.Data {
-    data[k] = v
+  if data == nil {
+    data = ConfigData{}
   }
 
-  for _, envVar := range os.Environ() {
-    pair := strings.Split(envVar, "=")
+  envVars := os.Environ()
+
+  // Sorted so that DB is always applied before DB__HOST, whatever
+  //  order the environment comes in
+  sort.Strings(envVars)
+
+  result := c.derive(data, defaults)
+
+  for _, envVar := range envVars {
+    pair := strings.SplitN(envVar, "=", 2)
 
-    k := strings.ToLower(pair[0])
+    if strings.HasPrefix(pair[0], prefix) == false || len(pair[0]) == len(prefix) {
+      continue
+    }
+
+    path := envVarPath(strings.TrimPrefix(pair[0], prefix))
     v := pair[1]
 
-    data[k] = v
+    if len(path) > 0 {
+      data.setPath(path, v)
+      addOrigins(result.origins, strings.Join(path, "."), v, constantOrigin(Origin{Kind: EnvVarOrigin, Name: pair[0]}))
+    }
   }
 
-  return &Config{Data: data}
+  return result
 }
 
-func Load(path string) (*Config, *errtrace.Error) {
-  configData, err := loadConfigData(path)
+func envVarPath(name string) []string {
+  name = strings.ToLower(name)
 
-  if configData != nil {
-    return &Config{Data: *configData}, err
-  } else {
-    return nil, err
+  if EnvVarSeparator == "" {
+    return []string{name}
+  }
+
+  path := []string{}
+
+  for _, segment := range strings.Split(name, EnvVarSeparator) {
+    if segment != "" {
+      path = append(path, segment)
+    }
   }
+
+  return path
+}
+
+// The format is picked from the file extension, see LoadFormat
+func Load(path string) (*Config, *errtrace.Error) {
+  return LoadFormat(path, formatFromPath(path))
 }
 
 func LoadP(path string) *Config {
@@ -180,14 +284,28 @@ func LoadP(path string) *Config {
   return c
 }
 
-func LoadSection(path string, section string) (*Config, *errtrace.Error) {
-  configData, err := loadConfigDataWithSubSection(path, section)
+func LoadFormat(path string, format Format) (*Config, *errtrace.Error) {
+  contents, err := readFile(path)
 
-  if configData != nil {
-    return &Config{Data: *configData}, err
-  } else {
+  if err != nil {
     return nil, err
   }
+
+  return loadConfig(path, contents, "", format)
+}
+
+func LoadFormatP(path string, format Format) *Config {
+  c, err := LoadFormat(path, format)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
+
+func LoadSection(path string, section string) (*Config, *errtrace.Error) {
+  return LoadSectionFormat(path, section, formatFromPath(path))
 }
 
 func LoadSectionP(path string, section string) *Config {
@@ -200,27 +318,173 @@ func LoadSectionP(path string, section string) *Config {
   return c
 }
 
+func LoadSectionFormat(path string, section string, format Format) (*Config, *errtrace.Error) {
+  contents, err := readFile(path)
+
+  if err != nil {
+    return nil, err
+  }
+
+  return loadConfig(path, contents, section, format)
+}
+
+func LoadSectionFormatP(path string, section string, format Format) *Config {
+  c, err := LoadSectionFormat(path, section, format)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
+
+// Reads yaml from anything, for example stdin or an http body.
+//  Use LoadBytesFormat for other formats.
+func LoadReader(r io.Reader) (*Config, *errtrace.Error) {
+  contents, err := ioutil.ReadAll(r)
+
+  if err != nil {
+    return nil, errtrace.Wrap(err)
+  }
+
+  return LoadBytes(contents)
+}
+
+func LoadReaderP(r io.Reader) *Config {
+  c, err := LoadReader(r)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
+
+func LoadReaderSection(r io.Reader, section string) (*Config, *errtrace.Error) {
+  contents, err := ioutil.ReadAll(r)
+
+  if err != nil {
+    return nil, errtrace.Wrap(err)
+  }
+
+  return LoadBytesSection(contents, section)
+}
+
+func LoadReaderSectionP(r io.Reader, section string) *Config {
+  c, err := LoadReaderSection(r, section)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
+
+func LoadBytes(contents []byte) (*Config, *errtrace.Error) {
+  return LoadBytesFormat(contents, YAML)
+}
+
+func LoadBytesP(contents []byte) *Config {
+  c, err := LoadBytes(contents)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
+
+func LoadBytesFormat(contents []byte, format Format) (*Config, *errtrace.Error) {
+  return loadConfig("", contents, "", format)
+}
+
+func LoadBytesFormatP(contents []byte, format Format) *Config {
+  c, err := LoadBytesFormat(contents, format)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
+
+func LoadBytesSection(contents []byte, section string) (*Config, *errtrace.Error) {
+  return LoadBytesSectionFormat(contents, section, YAML)
+}
+
+func LoadBytesSectionP(contents []byte, section string) *Config {
+  c, err := LoadBytesSection(contents, section)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
+
+func LoadBytesSectionFormat(contents []byte, section string, format Format) (*Config, *errtrace.Error) {
+  return loadConfig("", contents, section, format)
+}
+
+func LoadBytesSectionFormatP(contents []byte, section string, format Format) *Config {
+  c, err := LoadBytesSectionFormat(contents, section, format)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
+
+// Name is the file the contents were read from, empty for bytes.
+//  The section is left out when empty.
+func loadConfig(name string, contents []byte, section string, format Format) (*Config, *errtrace.Error) {
+  configData, err := parseConfigData(contents, format)
+
+  if err != nil {
+    return nil, err
+  }
+
+  if section != "" {
+    configData, err = configData.SubSection(section)
+
+    if err != nil {
+      return nil, err
+    }
+  }
+
+  lines := keyLines(contents, format)
+
+  origins := dataOrigins(*configData, func(path string) Origin {
+    return Origin{Kind: FileOrigin, Name: name, Line: lines[strings.ToLower(joinPath(section, path))], Format: format}
+  })
+
+  return &Config{Data: *configData, origins: origins}, nil
+}
 
 // Path is split into two to prevent creating boxes with unnecessary files
 //  for example packs.New("Whatever", "./") would compile all files in the project
 //  and include it in the binary
-func loadConfigData(path string) (*ConfigData, *errtrace.Error) {
+func readFile(path string) ([]byte, *errtrace.Error) {
   pathToDir := filepath.Dir(path)
   fileName := filepath.Base(path)
 
-  box := packr.New(fmt.Sprintln("Config - %s", pathToDir), pathToDir)
+  box := packr.New(fmt.Sprintf("Config - %s", pathToDir), pathToDir)
 
-  configInYaml, err := box.FindString(fileName)
+  contents, err := box.FindString(fileName)
 
   if err != nil {
     return nil, errtrace.Wrap(err)
   }
 
-  configData := ConfigData{}
-  err = yaml.Unmarshal([]byte(configInYaml), &configData)
+  return []byte(contents), nil
+}
+
+func parseConfigData(contents []byte, format Format) (*ConfigData, *errtrace.Error) {
+  configData, err := decodeConfigData(contents, format)
 
   if err != nil {
-    return nil, errtrace.Wrap(err)
+    return nil, err
   }
 
   configDataDowncased := make(ConfigData, len(configData))
@@ -233,14 +497,52 @@ func loadConfigData(path string) (*ConfigData, *errtrace.Error) {
   return &configDataDowncased, nil
 }
 
-func loadConfigDataWithSubSection(path string, subSection string) (*ConfigData, *errtrace.Error) {
-  configData, err := loadConfigData(path)
+// Missing or non-map sections along the path are replaced with maps,
+//  existing ones are kept so that their other keys survive
+func (c ConfigData) setPath(path []string, value interface{}) {
+  if len(path) == 1 {
+    c[path[0]] = value
+    return
+  }
 
-  if err == nil {
-    return configData.SubSection(subSection)
-  } else {
-    return nil, errtrace.Wrap(err)
+  section, isMap := toMap(c[path[0]])
+
+  if isMap == false {
+    section = make(map[interface{}]interface{})
+  }
+
+  setNestedPath(section, path[1:], value)
+  c[path[0]] = section
+}
+
+func setNestedPath(section map[interface{}]interface{}, path []string, value interface{}) {
+  if len(path) == 1 {
+    section[path[0]] = value
+    return
+  }
+
+  subSection, isMap := toMap(section[path[0]])
+
+  if isMap == false {
+    subSection = make(map[interface{}]interface{})
   }
+
+  setNestedPath(subSection, path[1:], value)
+  section[path[0]] = subSection
+}
+
+func (c ConfigData) copy() ConfigData {
+  if c == nil {
+    return nil
+  }
+
+  result := make(ConfigData, len(c))
+
+  for k, v := range c {
+    result[k] = deepCopy(v)
+  }
+
+  return result
 }
 
 func (c *ConfigData) SubSection(name string) (*ConfigData, *errtrace.Error) {
@@ -253,14 +555,122 @@ func (c *ConfigData) SubSection(name string) (*ConfigData, *errtrace.Error) {
   }
 
   if subSection == nil {
-    subSection = make(map[interface{}]interface{})
+    return &result, nil
+  }
+
+  section, isMap := toMap(subSection)
+
+  if isMap == false {
+    return nil, errtrace.New(fmt.Sprintf("Could not read sub-section: %s (not a map)", name))
   }
 
-  for k, v := range subSection.(map[interface{}]interface{}) {
-    key := strings.ToLower(k.(string))
+  for k, v := range section {
+    key := strings.ToLower(fmt.Sprintf("%v", k))
     result[key] = v
   }
 
   return &result, nil
 }
 
+
+// Times (from toml) are formatted the way yaml and json files write them
+func stringify(value interface{}) string {
+  switch v := value.(type) {
+    case nil:
+      return ""
+    case time.Time:
+      return v.Format(time.RFC3339Nano)
+    default:
+      return fmt.Sprintf("%v", v)
+  }
+}
+
+// A new config with the given data that keeps everything else the
+//  receiver knows about its keys, like which ones are secret and where
+//  their values came from
+func (c *Config) derive(data ConfigData, defaults ConfigData) *Config {
+  return &Config{Data: data, defaults: defaults, secrets: c.secretKeys(), origins: c.originsCopy()}
+}
+
+// Deep copies taken under the read lock, so they can be used without holding it
+func (c *Config) snapshot() (ConfigData, ConfigData) {
+  c.mutex.RLock()
+  defer c.mutex.RUnlock()
+
+  return c.Data.copy(), c.defaults.copy()
+}
+
+func (c ConfigData) get(key string) (interface{}, *errtrace.Error) {
+  v, found := c[key]
+
+  if found {
+    return v, nil
+  } else {
+    return c.lookup(key)
+  }
+}
+
+// Tells whether a value that is not a map stands on the path to key,
+//  like "db: scalar" does for "db.port". Merging replaces the defaults
+//  under such a value, so they must not show through it either.
+func (c ConfigData) shadows(key string) bool {
+  segments := strings.Split(key, ".")
+
+  for i := 1; i < len(segments); i++ {
+    value, err := c.get(strings.Join(segments[:i], "."))
+
+    if err != nil {
+      return false
+    }
+
+    if _, isMap := toMap(value); isMap == false {
+      return true
+    }
+  }
+
+  return false
+}
+
+// Keys with dots are paths into nested maps, so "db.pool.max" reads
+//  the "max" key of the "pool" map inside the "db" map. Numeric segments
+//  index into lists, so "servers.0.host" reads the host of the first server.
+func (c ConfigData) lookup(path string) (interface{}, *errtrace.Error) {
+  segments := strings.Split(path, ".")
+  var current interface{} = c
+
+  for i, segment := range segments {
+    var value interface{}
+    found := false
+
+    switch m := current.(type) {
+      case ConfigData:
+        value, found = m[segment]
+      case map[string]interface{}:
+        value, found = m[segment]
+      case map[interface{}]interface{}:
+        value, found = m[segment]
+      case []interface{}:
+        index, err := strconv.Atoi(segment)
+
+        if err == nil && index >= 0 && index < len(m) {
+          value, found = m[index], true
+        }
+      default:
+        parent := strings.Join(segments[:i], ".")
+        return nil, errtrace.New(fmt.Sprintf("Could not read key: %s (%s is not a map)", path, parent))
+    }
+
+    if found == false && len(segments) == 1 {
+      return nil, errtrace.New(fmt.Sprintf("Could not read key: %s", path))
+    }
+
+    if found == false {
+      missing := strings.Join(segments[:i+1], ".")
+      return nil, errtrace.New(fmt.Sprintf("Could not read key: %s (missing: %s)", path, missing))
+    }
+
+    current = value
+  }
+
+  return current, nil
+}
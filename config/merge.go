@@ -0,0 +1,99 @@
+package config
+
+func mergeData(this ConfigData, that ConfigData) ConfigData {
+  if this == nil && that == nil {
+    return nil
+  }
+
+  data := this.copy()
+
+  if data == nil {
+    data = ConfigData{}
+  }
+
+  for k, v := range that {
+    existing, found := data[k]
+
+    if found {
+      data[k] = mergeValues(existing, v)
+    } else {
+      data[k] = deepCopy(v)
+    }
+  }
+
+  return data
+}
+
+// Nested maps are combined key by key, anything else in that replaces
+//  the value in this. The result never shares maps or slices with its inputs.
+func mergeValues(this interface{}, that interface{}) interface{} {
+  thisMap, thisIsMap := toMap(this)
+  thatMap, thatIsMap := toMap(that)
+
+  if thisIsMap == false || thatIsMap == false {
+    return deepCopy(that)
+  }
+
+  result := deepCopy(thisMap).(map[interface{}]interface{})
+
+  for k, v := range thatMap {
+    existing, found := result[k]
+
+    if found {
+      result[k] = mergeValues(existing, v)
+    } else {
+      result[k] = deepCopy(v)
+    }
+  }
+
+  return result
+}
+
+// Nested maps always come out as map[interface{}]interface{}, the same
+//  shape yaml uses, no matter what kind of map was put in
+func deepCopy(value interface{}) interface{} {
+  if m, isMap := toMap(value); isMap {
+    result := make(map[interface{}]interface{}, len(m))
+
+    for k, v := range m {
+      result[k] = deepCopy(v)
+    }
+
+    return result
+  }
+
+  if s, isSlice := value.([]interface{}); isSlice {
+    result := make([]interface{}, len(s))
+
+    for i, v := range s {
+      result[i] = deepCopy(v)
+    }
+
+    return result
+  }
+
+  return value
+}
+
+func toMap(value interface{}) (map[interface{}]interface{}, bool) {
+  switch m := value.(type) {
+    case map[interface{}]interface{}:
+      return m, true
+    case map[string]interface{}:
+      return stringKeyedToMap(m), true
+    case ConfigData:
+      return stringKeyedToMap(m), true
+    default:
+      return nil, false
+  }
+}
+
+func stringKeyedToMap(m map[string]interface{}) map[interface{}]interface{} {
+  result := make(map[interface{}]interface{}, len(m))
+
+  for k, v := range m {
+    result[k] = v
+  }
+
+  return result
+}
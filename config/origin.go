@@ -0,0 +1,314 @@
+package config
+
+import (
+  "fmt"
+  "sort"
+  "strings"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+type OriginKind string
+
+const (
+  FileOrigin OriginKind = "file"
+  EnvVarOrigin OriginKind = "env"
+  FlagOrigin OriginKind = "flag"
+  SetFlagOrigin OriginKind = "--set"
+  SetCallOrigin OriginKind = "Set"
+  DefaultOrigin OriginKind = "default"
+)
+
+// Where a value came from. Name is the file, the env var, the flag,
+//  --set or --set-string, the file calling Set, or the struct the
+//  default was read from. Line is 0 when it is not known. Format is only
+//  set for values loaded from files.
+type Origin struct {
+  Kind OriginKind
+  Name string
+  Line int
+  Format Format
+  Value interface{}
+}
+
+func (o Origin) String() string {
+  switch o.Kind {
+    case FileOrigin, SetCallOrigin:
+      name := o.Name
+
+      if name == "" {
+        name = "loaded bytes"
+      }
+
+      if o.Line > 0 {
+        name = fmt.Sprintf("%s:%d", name, o.Line)
+      }
+
+      if o.Kind == SetCallOrigin {
+        return fmt.Sprintf("Set at %s", name)
+      }
+
+      return name
+    case EnvVarOrigin:
+      return fmt.Sprintf("env var %s", o.Name)
+    case FlagOrigin:
+      return fmt.Sprintf("flag -%s", o.Name)
+    case SetFlagOrigin:
+      return o.Name
+    case DefaultOrigin:
+      if o.Name == "" {
+        return "defaults"
+      }
+
+      return fmt.Sprintf("default tag of %s", o.Name)
+    default:
+      return string(o.Kind)
+  }
+}
+
+// Every value that was set under key, the one in effect first, followed by
+//  the ones it overrode. Defaults always come last, they stay underneath
+//  whatever order configs are merged in.
+func (c *Config) Origins(key string) []Origin {
+  c.mutex.RLock()
+  defer c.mutex.RUnlock()
+
+  chain, found := c.origins[key]
+
+  if found == false {
+    for path, origins := range c.origins {
+      if strings.EqualFold(path, key) {
+        chain = origins
+        break
+      }
+    }
+  }
+
+  result := make([]Origin, 0, len(chain))
+
+  for i := len(chain) - 1; i >= 0; i-- {
+    if chain[i].Kind != DefaultOrigin {
+      result = append(result, chain[i])
+    }
+  }
+
+  for i := len(chain) - 1; i >= 0; i-- {
+    if chain[i].Kind == DefaultOrigin {
+      result = append(result, chain[i])
+    }
+  }
+
+  return result
+}
+
+// Describes where the value under key and every value it overrode came
+//  from. For a section, every key inside it is explained. Secret values
+//  are redacted.
+//
+//    db.host
+//      "db.internal" from env var DB__HOST
+//      "localhost" from config.yaml:3, overridden
+func (c *Config) Explain(key string) (string, *errtrace.Error) {
+  secrets := c.secretKeys()
+  paths := c.originPaths(key)
+
+  if len(paths) == 0 {
+    return "", errtrace.New(fmt.Sprintf("Could not explain key: %s (no origin recorded)", key))
+  }
+
+  lines := []string{}
+
+  for _, path := range paths {
+    lines = append(lines, path)
+
+    for i, origin := range c.Origins(path) {
+      value := explainedValue(origin.Value)
+
+      if isSecret(secrets, path) && origin.Value != nil {
+        value = Redacted
+      }
+
+      line := fmt.Sprintf("  %s from %s", value, origin)
+
+      if i > 0 {
+        line = fmt.Sprintf("%s, overridden", line)
+      }
+
+      lines = append(lines, line)
+    }
+  }
+
+  return strings.Join(lines, "\n"), nil
+}
+
+func (c *Config) ExplainP(key string) string {
+  explanation, err := c.Explain(key)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return explanation
+}
+
+func (c *Config) originPaths(key string) []string {
+  c.mutex.RLock()
+  defer c.mutex.RUnlock()
+
+  paths := []string{}
+
+  for path := range c.origins {
+    if strings.EqualFold(path, key) || strings.HasPrefix(strings.ToLower(path), strings.ToLower(key) + ".") {
+      paths = append(paths, path)
+    }
+  }
+
+  sort.Strings(paths)
+
+  return paths
+}
+
+// The origin of the value in effect under path. Values inside a list or a
+//  section that was set as a whole take the origin of that list or section.
+//  Defaults only count when nothing else set the value.
+func currentOrigin(origins map[string][]Origin, path string) (Origin, bool) {
+  for path != "" {
+    chain := origins[path]
+
+    for i := len(chain) - 1; i >= 0; i-- {
+      if chain[i].Kind != DefaultOrigin {
+        return chain[i], true
+      }
+    }
+
+    if len(chain) > 0 {
+      return chain[len(chain)-1], true
+    }
+
+    dot := strings.LastIndex(path, ".")
+
+    if dot < 0 {
+      break
+    }
+
+    path = path[:dot]
+  }
+
+  return Origin{}, false
+}
+
+func (c *Config) originsCopy() map[string][]Origin {
+  c.mutex.RLock()
+  defer c.mutex.RUnlock()
+
+  return copyOrigins(c.origins)
+}
+
+func explainedValue(value interface{}) string {
+  switch v := value.(type) {
+    case nil:
+      return "null"
+    case string:
+      return fmt.Sprintf("%q", v)
+    default:
+      return stringify(v)
+  }
+}
+
+func copyOrigins(origins map[string][]Origin) map[string][]Origin {
+  result := make(map[string][]Origin, len(origins))
+
+  for path, chain := range origins {
+    result[path] = append([]Origin{}, chain...)
+  }
+
+  return result
+}
+
+// Chains of that are appended to the ones of this, as that overrides this.
+//  Values of that which replace sections of this, or the other way around,
+//  drop the chains they make obsolete first.
+func mergeOrigins(this map[string][]Origin, that map[string][]Origin) map[string][]Origin {
+  result := copyOrigins(this)
+
+  for path, chain := range that {
+    if origin, found := currentOrigin(that, path); found && origin.Kind != DefaultOrigin && len(chain) > 0 {
+      overrideOrigins(result, path, chain[len(chain)-1].Value, false, false)
+    }
+  }
+
+  for path, chain := range that {
+    result[path] = append(result[path], chain...)
+  }
+
+  return result
+}
+
+// Drops the chains a new value under path makes obsolete. A value that is
+//  not a map, or any value when replace is set, replaces everything below
+//  path. A value below a key that held something other than a map turns
+//  that key into a map, so the chain of that key goes too. Only lists that
+//  are patched in place, as --set does, keep theirs.
+func overrideOrigins(origins map[string][]Origin, path string, value interface{}, replace bool, patchesLists bool) {
+  if _, isMap := toMap(value); replace || isMap == false {
+    prefix := path + "."
+
+    for descendant := range origins {
+      if strings.HasPrefix(descendant, prefix) {
+        delete(origins, descendant)
+      }
+    }
+  }
+
+  for dot := strings.LastIndex(path, "."); dot >= 0; dot = strings.LastIndex(path, ".") {
+    path = path[:dot]
+    chain := origins[path]
+
+    if len(chain) == 0 {
+      continue
+    }
+
+    _, isMap := toMap(chain[len(chain)-1].Value)
+    _, isList := chain[len(chain)-1].Value.([]interface{})
+
+    if isMap == false && (isList == false || patchesLists == false) {
+      delete(origins, path)
+    }
+  }
+}
+
+// Records an origin for every value under path. Maps are walked down to
+//  their leaves, lists are recorded whole, as Merge replaces them whole.
+func addOrigins(origins map[string][]Origin, path string, value interface{}, origin func(string) Origin) {
+  if m, isMap := toMap(value); isMap && len(m) > 0 {
+    for k, v := range m {
+      addOrigins(origins, joinPath(path, fmt.Sprintf("%v", k)), v, origin)
+    }
+
+    return
+  }
+
+  o := origin(path)
+  o.Value = value
+
+  if o.Kind != DefaultOrigin {
+    overrideOrigins(origins, path, value, false, o.Kind == SetFlagOrigin)
+  }
+
+  origins[path] = append(origins[path], o)
+}
+
+func dataOrigins(data ConfigData, origin func(string) Origin) map[string][]Origin {
+  origins := map[string][]Origin{}
+
+  for k, v := range data {
+    addOrigins(origins, k, v, origin)
+  }
+
+  return origins
+}
+
+func constantOrigin(origin Origin) func(string) Origin {
+  return func(string) Origin {
+    return origin
+  }
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+  "fmt"
+  "strings"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+// Keys (or dotted paths) that have to be present. Declare them up front
+//  and Check them once everything is loaded and merged, so that all the
+//  missing ones are reported together.
+type RequiredKeys []string
+
+// Keys with an empty (null) value count as missing. Defaults count as present.
+func (r RequiredKeys) Check(c *Config) *errtrace.Error {
+  missing := []string{}
+
+  for _, key := range r {
+    value, err := c.Get(key)
+
+    if err != nil || value == nil {
+      missing = append(missing, key)
+    }
+  }
+
+  if len(missing) > 0 {
+    return errtrace.New(fmt.Sprintf("Missing required keys: %s", strings.Join(missing, ", ")))
+  }
+
+  return nil
+}
+
+func (r RequiredKeys) CheckP(c *Config) {
+  err := r.Check(c)
+
+  if err != nil {
+    panic(err)
+  }
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+  "os"
+  "fmt"
+  "sort"
+  "strings"
+  "io/ioutil"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+// Suffix of env vars that point to a file holding the actual value
+const envVarFileSuffix = "_FILE"
+
+// Reads the files that env vars starting with prefix and ending in _FILE
+//  point to, the way docker and kubernetes mount secrets. With prefix MYAPP_,
+//  MYAPP_DB_PASSWORD_FILE=/run/secrets/db sets db_password to the contents
+//  of /run/secrets/db without its trailing newline. Names are otherwise
+//  handled like in MergeWithPrefixedEnvVars, so call it after that to let
+//  the files win over plain env vars. Every key set from a file is marked
+//  secret, see MarkSecret.
+//
+//  The prefix is required. Plenty of unrelated variables end in _FILE
+//  (SSL_CERT_FILE, PIP_CONFIG_FILE, ...) and reading all of them would pull
+//  their files into the config or fail on the ones that can't be read.
+func (c *Config) MergeWithPrefixedEnvVarFiles(prefix string) (*Config, *errtrace.Error) {
+  if prefix == "" {
+    return nil, errtrace.New("Could not merge env var files: a prefix is required")
+  }
+
+  return c.mergeEnvVarFiles(prefix)
+}
+
+func (c *Config) MergeWithPrefixedEnvVarFilesP(prefix string) *Config {
+  result, err := c.MergeWithPrefixedEnvVarFiles(prefix)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return result
+}
+
+func (c *Config) mergeEnvVarFiles(prefix string) (*Config, *errtrace.Error) {
+  data, defaults := c.snapshot()
+
+  if data == nil {
+    data = ConfigData{}
+  }
+
+  envVars := os.Environ()
+  sort.Strings(envVars)
+
+  result := c.derive(data, defaults)
+
+  for _, envVar := range envVars {
+    pair := strings.SplitN(envVar, "=", 2)
+    name := pair[0]
+
+    if strings.HasPrefix(name, prefix) == false || strings.HasSuffix(name, envVarFileSuffix) == false {
+      continue
+    }
+
+    path := envVarPath(strings.TrimSuffix(strings.TrimPrefix(name, prefix), envVarFileSuffix))
+
+    if len(path) == 0 {
+      continue
+    }
+
+    value, err := readSecretFile(name, pair[1])
+
+    if err != nil {
+      return nil, err
+    }
+
+    key := strings.Join(path, ".")
+
+    data.setPath(path, value)
+    addOrigins(result.origins, key, value, constantOrigin(Origin{Kind: EnvVarOrigin, Name: name}))
+
+    // Whatever comes from a secret file is a secret, whether its name says so or not
+    if containsString(result.secrets, key) == false {
+      result.secrets = append(result.secrets, key)
+    }
+  }
+
+  return result, nil
+}
+
+func readSecretFile(name string, path string) (string, *errtrace.Error) {
+  if path == "" {
+    return "", errtrace.New(fmt.Sprintf("Could not read %s: no file given", name))
+  }
+
+  contents, err := ioutil.ReadFile(path)
+
+  if err != nil {
+    return "", errtrace.New(fmt.Sprintf("Could not read %s: %s", name, err.Error()))
+  }
+
+  value := strings.TrimSuffix(string(contents), "\n")
+  value = strings.TrimSuffix(value, "\r")
+
+  return value, nil
+}
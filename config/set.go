@@ -0,0 +1,325 @@
+package config
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+// Highest list index a --set expression may use
+const maxSetIndex = 65535
+
+// Collects repeated --set or --set-string flags, use it with flag.Var
+//
+//    var values, stringValues config.SetValues
+//    flag.Var(&values, "set", "set a value, for example servers[0].port=80")
+//    flag.Var(&stringValues, "set-string", "set a value that is always a string")
+type SetValues []string
+
+func (s *SetValues) String() string {
+  return strings.Join(*s, ",")
+}
+
+func (s *SetValues) Set(value string) error {
+  *s = append(*s, value)
+  return nil
+}
+
+// Builds a config out of helm style expressions like a.b.c=value,x=y.
+//  Keys can index into lists with servers[1].port=81 and values in braces
+//  become lists, as in hosts={a,b}. Values from values are typed, so true,
+//  false, null and numbers become bool, nil, int and float64. Values from
+//  stringValues are always strings and are applied after values.
+//  Use a backslash to escape any of .[],={} in keys and values.
+//
+//  Merging the result replaces whole lists, like helm does. Use ApplySet
+//  to change a single list item and keep the rest of the list.
+func ParseSet(values []string, stringValues []string) (*Config, *errtrace.Error) {
+  return (&Config{Data: ConfigData{}}).ApplySet(values, stringValues)
+}
+
+func ParseSetP(values []string, stringValues []string) *Config {
+  c, err := ParseSet(values, stringValues)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return c
+}
+
+// Applies the same expressions as ParseSet to a copy of the config
+func (c *Config) ApplySet(values []string, stringValues []string) (*Config, *errtrace.Error) {
+  data, defaults := c.snapshot()
+
+  if data == nil {
+    data = ConfigData{}
+  }
+
+  result := c.derive(data, defaults)
+
+  for _, expression := range values {
+    err := applySetExpression(data, expression, true, result.origins)
+
+    if err != nil {
+      return nil, err
+    }
+  }
+
+  for _, expression := range stringValues {
+    err := applySetExpression(data, expression, false, result.origins)
+
+    if err != nil {
+      return nil, err
+    }
+  }
+
+  return result, nil
+}
+
+func (c *Config) ApplySetP(values []string, stringValues []string) *Config {
+  result, err := c.ApplySet(values, stringValues)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return result
+}
+
+type setPathElement struct {
+  key string
+  index int
+  isIndex bool
+}
+
+func applySetExpression(data ConfigData, expression string, typed bool, origins map[string][]Origin) *errtrace.Error {
+  position := 0
+  flagName := "--set"
+
+  if typed == false {
+    flagName = "--set-string"
+  }
+
+  for position < len(expression) {
+    path, next, err := scanSetKey(expression, position)
+
+    if err != nil {
+      return errtrace.New(fmt.Sprintf("Could not parse %s %q: %s", flagName, expression, err.Error()))
+    }
+
+    value, next, err := scanSetValue(expression, next, typed)
+
+    if err != nil {
+      return errtrace.New(fmt.Sprintf("Could not parse %s %q: %s", flagName, expression, err.Error()))
+    }
+
+    key := path[0].key
+    data[key] = setAtPath(data[key], path[1:], value)
+    addOrigins(origins, setPathString(path), value, constantOrigin(Origin{Kind: SetFlagOrigin, Name: flagName}))
+    position = next
+  }
+
+  return nil
+}
+
+// The dotted path Get reads the value with, like servers.1.port
+func setPathString(path []setPathElement) string {
+  segments := make([]string, len(path))
+
+  for i, element := range path {
+    if element.isIndex {
+      segments[i] = strconv.Itoa(element.index)
+    } else {
+      segments[i] = element.key
+    }
+  }
+
+  return strings.Join(segments, ".")
+}
+
+// Returns the path and the position right after the =
+func scanSetKey(expression string, position int) ([]setPathElement, int, error) {
+  path := []setPathElement{}
+  segment := []byte{}
+
+  endSegment := func() {
+    if len(segment) > 0 {
+      path = append(path, setPathElement{key: strings.ToLower(string(segment))})
+      segment = []byte{}
+    }
+  }
+
+  for i := position; i < len(expression); i++ {
+    c := expression[i]
+
+    switch c {
+      case '\\':
+        if i + 1 < len(expression) {
+          i++
+          segment = append(segment, expression[i])
+        }
+      case '.':
+        endSegment()
+      case '[':
+        endSegment()
+
+        closing := strings.IndexByte(expression[i:], ']')
+
+        if closing < 0 {
+          return nil, 0, fmt.Errorf("missing ] in key")
+        }
+
+        index, err := strconv.Atoi(expression[i+1:i+closing])
+
+        if err != nil || index < 0 {
+          return nil, 0, fmt.Errorf("invalid list index %q", expression[i+1:i+closing])
+        }
+
+        if index > maxSetIndex {
+          return nil, 0, fmt.Errorf("list index %d is higher than %d", index, maxSetIndex)
+        }
+
+        path = append(path, setPathElement{index: index, isIndex: true})
+        i += closing
+      case '=':
+        endSegment()
+
+        if len(path) == 0 || path[0].isIndex {
+          return nil, 0, fmt.Errorf("key has to start with a name")
+        }
+
+        return path, i + 1, nil
+      case ',':
+        return nil, 0, fmt.Errorf("key %q has no value", expression[position:i])
+      default:
+        segment = append(segment, c)
+    }
+  }
+
+  return nil, 0, fmt.Errorf("key %q has no value", expression[position:])
+}
+
+// Returns the value and the position after the comma that ends it
+func scanSetValue(expression string, position int, typed bool) (interface{}, int, error) {
+  if position < len(expression) && expression[position] == '{' {
+    items := []interface{}{}
+    item := []byte{}
+
+    for i := position + 1; i < len(expression); i++ {
+      c := expression[i]
+
+      switch c {
+        case '\\':
+          if i + 1 < len(expression) {
+            i++
+            item = append(item, expression[i])
+          }
+        case ',':
+          items = append(items, setLiteral(string(item), typed))
+          item = []byte{}
+        case '}':
+          if i > position + 1 {
+            items = append(items, setLiteral(string(item), typed))
+          }
+
+          if i + 1 < len(expression) && expression[i+1] != ',' {
+            return nil, 0, fmt.Errorf("unexpected %q after list", expression[i+1:])
+          }
+
+          return items, i + 2, nil
+        default:
+          item = append(item, c)
+      }
+    }
+
+    return nil, 0, fmt.Errorf("missing } in list")
+  }
+
+  value := []byte{}
+
+  for i := position; i < len(expression); i++ {
+    c := expression[i]
+
+    switch c {
+      case '\\':
+        if i + 1 < len(expression) {
+          i++
+          value = append(value, expression[i])
+        }
+      case ',':
+        return setLiteral(string(value), typed), i + 1, nil
+      default:
+        value = append(value, c)
+    }
+  }
+
+  return setLiteral(string(value), typed), len(expression), nil
+}
+
+// Numbers with leading zeros stay strings, they are usually ids or octal modes
+func setLiteral(value string, typed bool) interface{} {
+  if typed == false {
+    return value
+  }
+
+  switch value {
+    case "true":
+      return true
+    case "false":
+      return false
+    case "null":
+      return nil
+  }
+
+  if len(value) > 1 && value[0] == '0' && value[1] != '.' {
+    return value
+  }
+
+  if i, err := strconv.Atoi(value); err == nil {
+    return i
+  }
+
+  if f, err := strconv.ParseFloat(value, 64); err == nil && strings.ContainsAny(value, "0123456789") {
+    return f
+  }
+
+  return value
+}
+
+// Existing maps and lists along the path are changed in place, anything
+//  else is replaced. Lists are padded with nils up to the index.
+func setAtPath(current interface{}, path []setPathElement, value interface{}) interface{} {
+  if len(path) == 0 {
+    return value
+  }
+
+  element := path[0]
+
+  if element.isIndex {
+    list, isList := current.([]interface{})
+
+    if isList == false {
+      list = []interface{}{}
+    }
+
+    for len(list) <= element.index {
+      list = append(list, nil)
+    }
+
+    list[element.index] = setAtPath(list[element.index], path[1:], value)
+
+    return list
+  }
+
+  m, isMap := toMap(current)
+
+  if isMap == false {
+    m = make(map[interface{}]interface{})
+  }
+
+  m[element.key] = setAtPath(m[element.key], path[1:], value)
+
+  return m
+}
@@ -0,0 +1,523 @@
+package config
+
+import (
+  "fmt"
+  "time"
+  "reflect"
+  "strconv"
+  "strings"
+  "encoding"
+  "encoding/json"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Fills target, which has to be a pointer, with the config data.
+//  Struct fields are matched by their `config:"name"` tag, or by their
+//  lowercased name if they have no tag. Fields tagged `config:"-"` are skipped.
+//  Strings (typically from env vars) are converted to the type of the field
+//  and the `validate` tag rules are checked. Types with a converter added by
+//  RegisterConverter are read with it. All fields that could not be filled
+//  or are not valid are reported in one error.
+func (c *Config) Unmarshal(target interface{}) *errtrace.Error {
+  return unmarshal("", c.effectiveData(), target, c.encodesCollections)
+}
+
+func (c *Config) UnmarshalP(target interface{}) {
+  err := c.Unmarshal(target)
+
+  if err != nil {
+    panic(err)
+  }
+}
+
+// Same as Unmarshal but starts at the given key, which can be a dotted path
+func (c *Config) UnmarshalSection(section string, target interface{}) *errtrace.Error {
+  data, err := c.Get(section)
+
+  if err != nil {
+    return err
+  }
+
+  return unmarshal(section, data, target, c.encodesCollections)
+}
+
+func (c *Config) UnmarshalSectionP(section string, target interface{}) {
+  err := c.UnmarshalSection(section, target)
+
+  if err != nil {
+    panic(err)
+  }
+}
+
+func unmarshal(path string, data interface{}, target interface{}, encoded func(string) bool) *errtrace.Error {
+  value := reflect.ValueOf(target)
+
+  if value.Kind() != reflect.Ptr || value.IsNil() {
+    return errtrace.New(fmt.Sprintf("Could not unmarshal config into %T: target must be a non-nil pointer", target))
+  }
+
+  d := decoder{encoded: encoded}
+  d.decode(path, data, value.Elem())
+
+  return d.err()
+}
+
+type decoder struct {
+  failures []string
+  // Tells whether strings under a key may hold a list or a map
+  encoded func(string) bool
+}
+
+func (d *decoder) fail(path string, format string, args ...interface{}) {
+  if path == "" {
+    path = "(root)"
+  }
+
+  d.failures = append(d.failures, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+func (d *decoder) err() *errtrace.Error {
+  if len(d.failures) == 0 {
+    return nil
+  }
+
+  return errtrace.New(fmt.Sprintf("Could not unmarshal config:\n  %s", strings.Join(d.failures, "\n  ")))
+}
+
+func (d *decoder) decode(path string, raw interface{}, target reflect.Value) {
+  if raw == nil {
+    return
+  }
+
+  if target.Kind() == reflect.Ptr {
+    if target.IsNil() {
+      target.Set(reflect.New(target.Type().Elem()))
+    }
+
+    d.decode(path, raw, target.Elem())
+    return
+  }
+
+  if convert, found := converterFor(target.Type()); found {
+    value, err := convert(raw)
+
+    if err != nil {
+      d.fail(path, "cannot convert %q to %s: %s", stringify(raw), target.Type(), err.Error())
+      return
+    }
+
+    if value == nil {
+      target.Set(reflect.Zero(target.Type()))
+    } else {
+      target.Set(reflect.ValueOf(value))
+    }
+
+    return
+  }
+
+  if s, isString := raw.(string); isString && target.CanAddr() && target.Addr().Type().Implements(textUnmarshalerType) {
+    err := target.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+
+    if err != nil {
+      d.fail(path, "cannot convert %q to %s: %s", s, target.Type(), err.Error())
+    }
+
+    return
+  }
+
+  if isScalar(raw) && reflect.TypeOf(raw).AssignableTo(target.Type()) && target.Kind() != reflect.Interface {
+    target.Set(reflect.ValueOf(raw))
+    return
+  }
+
+  if target.Type() == durationType {
+    d.decodeDuration(path, raw, target)
+    return
+  }
+
+  switch target.Kind() {
+    case reflect.Interface:
+      if target.NumMethod() == 0 {
+        target.Set(reflect.ValueOf(deepCopy(raw)))
+      } else {
+        d.fail(path, "cannot unmarshal into %s", target.Type())
+      }
+    case reflect.Struct:
+      d.decodeStruct(path, raw, target)
+    case reflect.Map:
+      d.decodeMap(path, raw, target)
+    case reflect.Slice:
+      d.decodeSlice(path, raw, target)
+    case reflect.Array:
+      d.decodeArray(path, raw, target)
+    default:
+      d.decodeScalar(path, raw, target)
+  }
+}
+
+func (d *decoder) decodeStruct(path string, raw interface{}, target reflect.Value) {
+  m, isMap := toMap(raw)
+
+  if isMap == false {
+    d.fail(path, "cannot convert %v to %s, expected a map", raw, target.Type())
+    return
+  }
+
+  targetType := target.Type()
+
+  for i := 0; i < targetType.NumField(); i++ {
+    field := targetType.Field(i)
+
+    if field.PkgPath != "" {
+      continue
+    }
+
+    name, tagged := fieldKey(field)
+
+    if name == "-" {
+      continue
+    }
+
+    if field.Anonymous && tagged == false && indirectType(field.Type).Kind() == reflect.Struct {
+      d.decode(path, m, target.Field(i))
+      continue
+    }
+
+    fieldPath := joinPath(path, name)
+    rules := parseRules(field.Tag.Get("validate"))
+    value, found := mapValue(m, name)
+
+    if found == false || value == nil {
+      if isRequired(rules) {
+        d.fail(fieldPath, "is required")
+      }
+
+      // Still walked through so that required keys inside are reported too.
+      //  Sections behind a pointer are optional, the pointer stays nil.
+      if field.Type.Kind() == reflect.Struct {
+        d.decodeStruct(fieldPath, map[interface{}]interface{}{}, target.Field(i))
+      }
+
+      continue
+    }
+
+    failures := len(d.failures)
+    d.decode(fieldPath, value, target.Field(i))
+
+    if len(d.failures) == failures {
+      d.validate(fieldPath, rules, target.Field(i))
+    }
+  }
+}
+
+func (d *decoder) decodeMap(path string, raw interface{}, target reflect.Value) {
+  m, isMap := mapItems(raw, d.encodedAt(path))
+
+  if isMap == false {
+    d.fail(path, "cannot convert %v to %s, expected a map", raw, target.Type())
+    return
+  }
+
+  targetType := target.Type()
+
+  if target.IsNil() {
+    target.Set(reflect.MakeMap(targetType))
+  }
+
+  for k, v := range m {
+    keyPath := joinPath(path, fmt.Sprintf("%v", k))
+    key := reflect.New(targetType.Key()).Elem()
+    d.decode(keyPath, fmt.Sprintf("%v", k), key)
+
+    value := reflect.New(targetType.Elem()).Elem()
+    d.decode(keyPath, v, value)
+
+    target.SetMapIndex(key, value)
+  }
+}
+
+func (d *decoder) decodeSlice(path string, raw interface{}, target reflect.Value) {
+  items, ok := sliceItems(raw, d.encodedAt(path))
+
+  if ok == false {
+    d.fail(path, "cannot convert %v to %s, expected a list", raw, target.Type())
+    return
+  }
+
+  result := reflect.MakeSlice(target.Type(), len(items), len(items))
+
+  for i, item := range items {
+    d.decode(fmt.Sprintf("%s[%d]", path, i), item, result.Index(i))
+  }
+
+  target.Set(result)
+}
+
+func (d *decoder) decodeArray(path string, raw interface{}, target reflect.Value) {
+  items, ok := sliceItems(raw, d.encodedAt(path))
+
+  if ok == false {
+    d.fail(path, "cannot convert %v to %s, expected a list", raw, target.Type())
+    return
+  }
+
+  if len(items) != target.Len() {
+    d.fail(path, "cannot convert %d items to %s", len(items), target.Type())
+    return
+  }
+
+  for i, item := range items {
+    d.decode(fmt.Sprintf("%s[%d]", path, i), item, target.Index(i))
+  }
+}
+
+func (d *decoder) decodeDuration(path string, raw interface{}, target reflect.Value) {
+  switch v := raw.(type) {
+    case string:
+      duration, err := time.ParseDuration(v)
+
+      if err != nil {
+        d.fail(path, "cannot convert %q to %s", v, target.Type())
+        return
+      }
+
+      target.SetInt(int64(duration))
+    default:
+      d.decodeScalar(path, raw, target)
+  }
+}
+
+func (d *decoder) decodeScalar(path string, raw interface{}, target reflect.Value) {
+  if _, isMap := toMap(raw); isMap {
+    d.fail(path, "cannot convert a map to %s", target.Type())
+    return
+  }
+
+  if _, isSlice := raw.([]interface{}); isSlice {
+    d.fail(path, "cannot convert a list to %s", target.Type())
+    return
+  }
+
+  s := stringify(raw)
+
+  switch target.Kind() {
+    case reflect.String:
+      target.SetString(s)
+    case reflect.Bool:
+      v, err := strconv.ParseBool(s)
+
+      if err != nil {
+        d.fail(path, "cannot convert %q to %s", s, target.Type())
+        return
+      }
+
+      target.SetBool(v)
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+      v, err := strconv.ParseInt(s, 10, target.Type().Bits())
+
+      if err != nil {
+        d.fail(path, "cannot convert %q to %s", s, target.Type())
+        return
+      }
+
+      target.SetInt(v)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+      v, err := strconv.ParseUint(s, 10, target.Type().Bits())
+
+      if err != nil {
+        d.fail(path, "cannot convert %q to %s", s, target.Type())
+        return
+      }
+
+      target.SetUint(v)
+    case reflect.Float32, reflect.Float64:
+      v, err := strconv.ParseFloat(s, target.Type().Bits())
+
+      if err != nil {
+        d.fail(path, "cannot convert %q to %s", s, target.Type())
+        return
+      }
+
+      target.SetFloat(v)
+    default:
+      d.fail(path, "cannot unmarshal into %s", target.Type())
+  }
+}
+
+func isScalar(raw interface{}) bool {
+  if _, isMap := toMap(raw); isMap {
+    return false
+  }
+
+  _, isSlice := raw.([]interface{})
+
+  return isSlice == false
+}
+
+// Paths of list items are written as servers[0], origins as servers.0
+func (d *decoder) encodedAt(path string) bool {
+  if d.encoded == nil {
+    return false
+  }
+
+  return d.encoded(strings.NewReplacer("[", ".", "]", "").Replace(path))
+}
+
+// Env vars, flags, dotenv files and default tags can only hold strings,
+//  so lists and maps come from them encoded. Strings from yaml, JSON and
+//  TOML files or from Set are taken as they are. Values written to Data
+//  directly have no origin and are treated as encoded.
+func (c *Config) encodesCollections(path string) bool {
+  c.mutex.RLock()
+  defer c.mutex.RUnlock()
+
+  origin, found := currentOrigin(c.origins, path)
+
+  if found == false {
+    return true
+  }
+
+  switch origin.Kind {
+    case EnvVarOrigin, FlagOrigin, SetFlagOrigin, DefaultOrigin:
+      return true
+    case FileOrigin:
+      return origin.Format == Dotenv
+    default:
+      return false
+  }
+}
+
+// Lists can also come as comma separated strings or JSON arrays when
+//  they are encoded
+func sliceItems(raw interface{}, encoded bool) ([]interface{}, bool) {
+  switch v := raw.(type) {
+    case []interface{}:
+      return v, true
+    case string:
+      if encoded == false {
+        return nil, false
+      }
+
+      if strings.TrimSpace(v) == "" {
+        return []interface{}{}, true
+      }
+
+      if list, isList := jsonString(v).([]interface{}); isList {
+        return list, true
+      }
+
+      parts := strings.Split(v, ",")
+      items := make([]interface{}, len(parts))
+
+      for i, part := range parts {
+        items[i] = strings.TrimSpace(part)
+      }
+
+      return items, true
+    default:
+      return nil, false
+  }
+}
+
+// Maps can also come as JSON objects or comma separated key=value pairs
+//  when they are encoded
+func mapItems(raw interface{}, encoded bool) (map[interface{}]interface{}, bool) {
+  if m, isMap := toMap(raw); isMap {
+    return m, true
+  }
+
+  s, isString := raw.(string)
+
+  if isString == false || encoded == false {
+    return nil, false
+  }
+
+  if m, isMap := jsonString(s).(map[interface{}]interface{}); isMap {
+    return m, true
+  }
+
+  result := map[interface{}]interface{}{}
+
+  if strings.TrimSpace(s) == "" {
+    return result, true
+  }
+
+  for _, pair := range strings.Split(s, ",") {
+    parts := strings.SplitN(pair, "=", 2)
+
+    if len(parts) != 2 {
+      return nil, false
+    }
+
+    result[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+  }
+
+  return result, true
+}
+
+// Decodes strings that hold a JSON array or object, anything else is nil
+func jsonString(s string) interface{} {
+  s = strings.TrimSpace(s)
+
+  if (strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{")) == false || json.Valid([]byte(s)) == false {
+    return nil
+  }
+
+  decoder := json.NewDecoder(strings.NewReader(s))
+  decoder.UseNumber()
+
+  var value interface{}
+
+  if decoder.Decode(&value) != nil {
+    return nil
+  }
+
+  return normalizeJson(value)
+}
+
+func fieldKey(field reflect.StructField) (string, bool) {
+  tag := field.Tag.Get("config")
+
+  if tag != "" {
+    return tag, true
+  }
+
+  return strings.ToLower(field.Name), false
+}
+
+// Top level keys are lowercased when loading but nested ones are not,
+//  so an exact match is preferred and a case-insensitive one accepted
+func mapValue(m map[interface{}]interface{}, name string) (interface{}, bool) {
+  value, found := m[name]
+
+  if found {
+    return value, true
+  }
+
+  for k, v := range m {
+    if key, isString := k.(string); isString && strings.EqualFold(key, name) {
+      return v, true
+    }
+  }
+
+  return nil, false
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+  for t.Kind() == reflect.Ptr {
+    t = t.Elem()
+  }
+
+  return t
+}
+
+func joinPath(path string, key string) string {
+  if path == "" {
+    return key
+  }
+
+  return fmt.Sprintf("%s.%s", path, key)
+}
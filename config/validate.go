@@ -0,0 +1,191 @@
+package config
+
+import (
+  "fmt"
+  "net"
+  "time"
+  "regexp"
+  "reflect"
+  "strconv"
+  "strings"
+  "net/url"
+)
+
+type rule struct {
+  name string
+  argument string
+}
+
+// Rules come from the `validate` tag, separated by commas. A regex rule
+//  takes the rest of the tag, so it has to be the last one if it contains commas.
+//
+//  required      the key has to be present
+//  min=N, max=N  bounds for numbers and durations, or for the length of
+//                strings, slices and maps
+//  oneof=a b c   the value has to be one of the space separated options
+//  regex=EXPR    the value has to match the regular expression
+//  url           the value has to be an absolute url
+//  hostport      the value has to be host:port with a valid port
+func parseRules(tag string) []rule {
+  rules := []rule{}
+
+  for tag != "" {
+    var part string
+
+    if strings.HasPrefix(tag, "regex=") {
+      part, tag = tag, ""
+    } else if i := strings.Index(tag, ","); i >= 0 {
+      part, tag = tag[:i], tag[i+1:]
+    } else {
+      part, tag = tag, ""
+    }
+
+    part = strings.TrimSpace(part)
+
+    if part == "" {
+      continue
+    }
+
+    pair := strings.SplitN(part, "=", 2)
+    r := rule{name: pair[0]}
+
+    if len(pair) == 2 {
+      r.argument = pair[1]
+    }
+
+    rules = append(rules, r)
+  }
+
+  return rules
+}
+
+func isRequired(rules []rule) bool {
+  for _, r := range rules {
+    if r.name == "required" {
+      return true
+    }
+  }
+
+  return false
+}
+
+func (d *decoder) validate(path string, rules []rule, value reflect.Value) {
+  for value.Kind() == reflect.Ptr {
+    if value.IsNil() {
+      return
+    }
+
+    value = value.Elem()
+  }
+
+  for _, r := range rules {
+    switch r.name {
+      case "required":
+      case "min", "max":
+        d.validateBound(path, r, value)
+      case "oneof":
+        s := fmt.Sprintf("%v", value.Interface())
+        options := strings.Fields(r.argument)
+
+        if containsString(options, s) == false {
+          d.fail(path, "must be one of [%s], got %q", strings.Join(options, " "), s)
+        }
+      case "regex":
+        expression, err := regexp.Compile(r.argument)
+
+        if err != nil {
+          d.fail(path, "invalid regex rule %q: %s", r.argument, err.Error())
+          continue
+        }
+
+        s := fmt.Sprintf("%v", value.Interface())
+
+        if expression.MatchString(s) == false {
+          d.fail(path, "must match %s, got %q", r.argument, s)
+        }
+      case "url":
+        s := fmt.Sprintf("%v", value.Interface())
+        u, err := url.Parse(s)
+
+        if err != nil || u.Scheme == "" || u.Host == "" {
+          d.fail(path, "must be an absolute url, got %q", s)
+        }
+      case "hostport":
+        s := fmt.Sprintf("%v", value.Interface())
+
+        if isHostPort(s) == false {
+          d.fail(path, "must be host:port, got %q", s)
+        }
+      default:
+        d.fail(path, "unknown validation rule %q", r.name)
+    }
+  }
+}
+
+func (d *decoder) validateBound(path string, r rule, value reflect.Value) {
+  var actual float64
+  var bound float64
+  var err error
+  subject := "be"
+
+  switch value.Kind() {
+    case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+      actual = float64(value.Len())
+      subject = "have a length of"
+      bound, err = strconv.ParseFloat(r.argument, 64)
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+      actual = float64(value.Int())
+
+      if value.Type() == durationType {
+        var duration time.Duration
+        duration, err = time.ParseDuration(r.argument)
+        bound = float64(duration)
+      } else {
+        bound, err = strconv.ParseFloat(r.argument, 64)
+      }
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+      actual = float64(value.Uint())
+      bound, err = strconv.ParseFloat(r.argument, 64)
+    case reflect.Float32, reflect.Float64:
+      actual = value.Float()
+      bound, err = strconv.ParseFloat(r.argument, 64)
+    default:
+      d.fail(path, "rule %s does not apply to %s", r.name, value.Type())
+      return
+  }
+
+  if err != nil {
+    d.fail(path, "invalid %s rule %q", r.name, r.argument)
+    return
+  }
+
+  if r.name == "min" && actual < bound {
+    d.fail(path, "must %s at least %s", subject, r.argument)
+  }
+
+  if r.name == "max" && actual > bound {
+    d.fail(path, "must %s at most %s", subject, r.argument)
+  }
+}
+
+func isHostPort(s string) bool {
+  host, port, err := net.SplitHostPort(s)
+
+  if err != nil || host == "" {
+    return false
+  }
+
+  portNumber, err := strconv.ParseUint(port, 10, 16)
+
+  return err == nil && portNumber > 0
+}
+
+func containsString(list []string, s string) bool {
+  for _, item := range list {
+    if item == s {
+      return true
+    }
+  }
+
+  return false
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+  "os"
+  "sync"
+  "time"
+  "sync/atomic"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+// How often Watch checks the files for changes
+var WatchInterval = time.Second
+
+// Keeps a config up to date with the files it was loaded from
+type Watcher struct {
+  load func() (*Config, *errtrace.Error)
+  paths []string
+  stamps map[string]fileStamp
+  current atomic.Value
+  mutex sync.Mutex
+  reloadMutex sync.Mutex
+  onChange []func(old *Config, new *Config)
+  onError []func(err *errtrace.Error)
+  done chan struct{}
+  stopOnce sync.Once
+}
+
+type fileStamp struct {
+  exists bool
+  size int64
+  modTime time.Time
+}
+
+// Runs load right away and then again whenever one of the paths changes on disk.
+//  load is the same pipeline you would use without watching, for example
+//
+//    func() (*config.Config, *errtrace.Error) {
+//      c, err := config.Load("config.yaml")
+//
+//      if err != nil {
+//        return nil, err
+//      }
+//
+//      return c.MergeWithEnvVars(), nil
+//    }
+//
+//  If a reload fails the last good config is kept and OnError callbacks are called.
+func Watch(load func() (*Config, *errtrace.Error), paths ...string) (*Watcher, *errtrace.Error) {
+  w := &Watcher{
+    load: load,
+    paths: paths,
+    stamps: stampFiles(paths),
+    done: make(chan struct{}),
+  }
+
+  c, err := load()
+
+  if err != nil {
+    return nil, err
+  }
+
+  w.current.Store(c)
+
+  go w.run()
+
+  return w, nil
+}
+
+func WatchP(load func() (*Config, *errtrace.Error), paths ...string) *Watcher {
+  w, err := Watch(load, paths...)
+
+  if err != nil {
+    panic(err)
+  }
+
+  return w
+}
+
+// The latest config that loaded successfully
+func (w *Watcher) Config() *Config {
+  return w.current.Load().(*Config)
+}
+
+// Called with the previous and the new config after every successful reload
+func (w *Watcher) OnChange(callback func(old *Config, new *Config)) {
+  w.mutex.Lock()
+  defer w.mutex.Unlock()
+
+  w.onChange = append(w.onChange, callback)
+}
+
+// Called when a reload fails, the previous config stays in place
+func (w *Watcher) OnError(callback func(err *errtrace.Error)) {
+  w.mutex.Lock()
+  defer w.mutex.Unlock()
+
+  w.onError = append(w.onError, callback)
+}
+
+// Reloads right away, whether the files changed or not. Reloads run one at
+//  a time, so a manual Reload racing with a change on disk can never replace
+//  a newer config with an older one.
+func (w *Watcher) Reload() *errtrace.Error {
+  w.reloadMutex.Lock()
+  c, err := w.load()
+
+  w.mutex.Lock()
+  old := w.Config()
+
+  if err == nil {
+    w.current.Store(c)
+  }
+
+  onChange := w.onChange
+  onError := w.onError
+  w.mutex.Unlock()
+  w.reloadMutex.Unlock()
+
+  if err != nil {
+    for _, callback := range onError {
+      callback(err)
+    }
+
+    return err
+  }
+
+  for _, callback := range onChange {
+    callback(old, c)
+  }
+
+  return nil
+}
+
+func (w *Watcher) Stop() {
+  w.stopOnce.Do(func() {
+    close(w.done)
+  })
+}
+
+func (w *Watcher) run() {
+  ticker := time.NewTicker(WatchInterval)
+  defer ticker.Stop()
+
+  for {
+    select {
+      case <-w.done:
+        return
+      case <-ticker.C:
+        stamps := stampFiles(w.paths)
+
+        if stampsEqual(w.stamps, stamps) == false {
+          w.stamps = stamps
+          w.Reload()
+        }
+    }
+  }
+}
+
+func stampFiles(paths []string) map[string]fileStamp {
+  stamps := make(map[string]fileStamp, len(paths))
+
+  for _, path := range paths {
+    info, err := os.Stat(path)
+
+    if err != nil {
+      stamps[path] = fileStamp{}
+    } else {
+      stamps[path] = fileStamp{exists: true, size: info.Size(), modTime: info.ModTime()}
+    }
+  }
+
+  return stamps
+}
+
+func stampsEqual(this map[string]fileStamp, that map[string]fileStamp) bool {
+  for path, stamp := range this {
+    other := that[path]
+
+    if stamp.exists != other.exists || stamp.size != other.size || stamp.modTime.Equal(other.modTime) == false {
+      return false
+    }
+  }
+
+  return true
+}
@@ -31,6 +31,9 @@ func main() {
   isAwesome, _ := mainConfig.GetBool("is_awesome")
   fmt.Println(fmt.Sprintf("IsAwesome: %t", isAwesome))
 
+  bar, _ := mainConfig.GetString("foo.bar")
+  fmt.Println(fmt.Sprintf("Bar: %s", bar))
+
   overrides, _ := config.LoadSection("examples/overrides.yaml", "env_vars")
 
   width, _ = overrides.GetString("width")
@@ -31,6 +31,9 @@ func main() {
   isAwesome := mainConfig.GetBoolP("is_awesome")
   fmt.Println(fmt.Sprintf("IsAwesome: %t", isAwesome))
 
+  bar := mainConfig.GetStringP("foo.bar")
+  fmt.Println(fmt.Sprintf("Bar: %s", bar))
+
   overrides := config.LoadSectionP("examples/overrides.yaml", "env_vars")
 
   height = overrides.GetStringP("heigth")
@@ -0,0 +1,174 @@
+package main
+
+import (
+  "os"
+  "reflect"
+  "strings"
+  "testing"
+  "app/config"
+)
+
+var collectionContents = []byte(`
+hosts: [a, b]
+numbers: [1, 2, 3]
+ratios: [0.5, 1]
+mixed: [1, "x"]
+empty:
+labels:
+  team: core
+  tier: 1
+db:
+  host: localhost
+  pool:
+    max: 10
+`)
+
+func TestCollectionGetters(t *testing.T) {
+  c := config.LoadBytesP(collectionContents)
+
+  if hosts := c.GetStringSliceP("hosts"); reflect.DeepEqual(hosts, []string{"a", "b"}) == false {
+    t.Errorf("Expected [a b], got %v", hosts)
+  }
+
+  if numbers := c.GetIntSliceP("numbers"); reflect.DeepEqual(numbers, []int{1, 2, 3}) == false {
+    t.Errorf("Expected [1 2 3], got %v", numbers)
+  }
+
+  if ratios := c.GetFloatSliceP("ratios"); reflect.DeepEqual(ratios, []float64{0.5, 1}) == false {
+    t.Errorf("Expected [0.5 1], got %v", ratios)
+  }
+
+  if empty := c.GetStringSliceP("empty"); len(empty) != 0 {
+    t.Errorf("Expected an empty list, got %v", empty)
+  }
+
+  labels := c.GetStringMapStringP("labels")
+
+  if reflect.DeepEqual(labels, map[string]string{"team": "core", "tier": "1"}) == false {
+    t.Errorf("Expected the labels, got %v", labels)
+  }
+
+  db := c.GetStringMapP("db")
+  db["host"] = "changed"
+
+  if db["pool"].(map[interface{}]interface{})["max"] != 10 || c.GetStringP("db.host") != "localhost" {
+    t.Errorf("Expected a copy of the section, got %v", db)
+  }
+}
+
+func TestCollectionGettersFromEnvVars(t *testing.T) {
+  envVars := map[string]string{
+    "COLLECTION_HOSTS": "x, y,z",
+    "COLLECTION_NUMBERS": "[4, 5]",
+    "COLLECTION_RATIOS": "0.1,2",
+    "COLLECTION_LABELS": `{"team": "web", "tier": 2}`,
+    "COLLECTION_ANNOTATIONS": "owner=ops, on_call=yes",
+  }
+
+  for name, value := range envVars {
+    os.Setenv(name, value)
+    defer os.Unsetenv(name)
+  }
+
+  c := config.LoadBytesP(collectionContents).MergeWithPrefixedEnvVars("COLLECTION_")
+
+  if hosts := c.GetStringSliceP("hosts"); reflect.DeepEqual(hosts, []string{"x", "y", "z"}) == false {
+    t.Errorf("Expected [x y z], got %v", hosts)
+  }
+
+  if numbers := c.GetIntSliceP("numbers"); reflect.DeepEqual(numbers, []int{4, 5}) == false {
+    t.Errorf("Expected [4 5], got %v", numbers)
+  }
+
+  if ratios := c.GetFloatSliceP("ratios"); reflect.DeepEqual(ratios, []float64{0.1, 2}) == false {
+    t.Errorf("Expected [0.1 2], got %v", ratios)
+  }
+
+  if labels := c.GetStringMapStringP("labels"); reflect.DeepEqual(labels, map[string]string{"team": "web", "tier": "2"}) == false {
+    t.Errorf("Expected the labels from json, got %v", labels)
+  }
+
+  if annotations := c.GetStringMapStringP("annotations"); reflect.DeepEqual(annotations, map[string]string{"owner": "ops", "on_call": "yes"}) == false {
+    t.Errorf("Expected the annotations from pairs, got %v", annotations)
+  }
+
+  settings := struct {
+    Numbers []int `config:"numbers"`
+    Labels map[string]string `config:"labels"`
+  }{}
+
+  c.UnmarshalP(&settings)
+
+  if reflect.DeepEqual(settings.Numbers, []int{4, 5}) == false || settings.Labels["team"] != "web" {
+    t.Errorf("Expected Unmarshal to read the same strings, got %v", settings)
+  }
+}
+
+func TestCollectionGettersKeepFileStrings(t *testing.T) {
+  os.Setenv("FILESTRINGS_TAGS", "a,b")
+  defer os.Unsetenv("FILESTRINGS_TAGS")
+
+  c := config.LoadBytesP([]byte("greeting: Hello, world\nlabels: team=core\n")).
+    MergeWithPrefixedEnvVars("FILESTRINGS_")
+
+  _, err := c.GetStringSlice("greeting")
+
+  if err == nil || strings.Contains(err.Error(), "Could not read key: greeting") == false {
+    t.Errorf("Expected a string from a file not to be split, got %v", err)
+  }
+
+  _, err = c.GetStringMap("labels")
+
+  if err == nil {
+    t.Errorf("Expected a string from a file not to be read as pairs")
+  }
+
+  settings := struct {
+    Greeting []string `config:"greeting"`
+    Tags []string `config:"tags"`
+  }{}
+
+  err = c.Unmarshal(&settings)
+
+  if err == nil || strings.Contains(err.Error(), "greeting: cannot convert") == false {
+    t.Errorf("Expected Unmarshal not to split the greeting, got %v", err)
+  }
+
+  if reflect.DeepEqual(settings.Tags, []string{"a", "b"}) == false {
+    t.Errorf("Expected the env var to be split, got %v", settings.Tags)
+  }
+
+  dotenv := config.LoadBytesFormatP([]byte("HOSTS=x,y\n"), config.Dotenv)
+
+  if hosts := dotenv.GetStringSliceP("hosts"); reflect.DeepEqual(hosts, []string{"x", "y"}) == false {
+    t.Errorf("Expected dotenv values to be split like env vars, got %v", hosts)
+  }
+}
+
+func TestCollectionGettersErrors(t *testing.T) {
+  c := config.LoadBytesP(collectionContents)
+
+  _, err := c.GetIntSlice("mixed")
+
+  if err == nil || strings.Contains(err.Error(), "Could not read key: mixed.1") == false {
+    t.Errorf("Expected the item to be named, got %v", err)
+  }
+
+  _, err = c.GetStringMapString("db")
+
+  if err == nil || strings.Contains(err.Error(), "Could not read key: db.pool") == false {
+    t.Errorf("Expected the nested map to be reported, got %v", err)
+  }
+
+  _, err = c.GetStringMap("hosts")
+
+  if err == nil || strings.Contains(err.Error(), "Could not read key: hosts") == false {
+    t.Errorf("Expected a list not to be a map, got %v", err)
+  }
+
+  _, err = c.GetStringSlice("labels")
+
+  if err == nil {
+    t.Errorf("Expected a map not to be a list")
+  }
+}
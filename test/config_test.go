@@ -3,8 +3,10 @@ package main
 import (
   "os"
   "fmt"
+  "strings"
   "testing"
   "io/ioutil"
+  "encoding/json"
   "app/config"
   "gopkg.in/yaml.v2"
 )
@@ -16,16 +18,44 @@ var primaryLength int = 200
 var numbers [3]string = [3]string{"one", "two", "three"}
 var primaryIsAwesome bool = false
 var primaryIsTerrible bool = true
+var primaryDbHost string = "localhost"
+var primaryDbPoolMax int = 10
 
 var secondaryFileName string = "./secondaryFile.yaml"
+var jsonFileName string = "./jsonFile.json"
+var jsonWidth int = 800
+var jsonDbPoolMax int = 40
+var tomlFileName string = "./tomlFile.toml"
+var tomlContents string = `Width = 900
+released = 2019-02-01T10:00:00Z
+
+[db.pool]
+max = 60
+
+[[servers]]
+host = "alpha"
+
+[[servers]]
+host = "beta"
+
+[env_vars]
+HEIGHT = 950
+`
 var secondaryWidth int = 400
 var secondaryHeight int = 400
+var secondaryDbPoolMax int = 20
+var productionSection string = "production"
 
 var tertiaryHeight int = 600
 var heroName string = "Jon"
 var section string = "env_vars"
 var tertiaryIsAwesome bool = true
 var tertiaryIsTerrible bool = false
+var envVarPrefix string = "MYAPP_"
+var prefixedWidth int = 300
+var prefixedName string = "Arya"
+var envDbPoolMax int = 30
+var envDbPoolIdle int = 5
 
 func writeYaml(path string, data map[string]interface{}) {
   contents, err := yaml.Marshal(&data)
@@ -41,6 +71,20 @@ func writeYaml(path string, data map[string]interface{}) {
   }
 }
 
+func writeJson(path string, data map[string]interface{}) {
+  contents, err := json.Marshal(&data)
+
+  if err != nil {
+    panic(err)
+  }
+
+  err = ioutil.WriteFile(path, contents, 0644)
+
+  if err != nil {
+    panic(err)
+  }
+}
+
 func setup() {
   writeYaml(mainFileName, map[string]interface{}{
     "WIDTH": primaryWidth,
@@ -49,6 +93,12 @@ func setup() {
     "numbers": numbers,
     "is_awesome": primaryIsAwesome,
     "is_terrible": primaryIsTerrible,
+    "db": map[string]interface{}{
+      "host": primaryDbHost,
+      "pool": map[string]interface{}{
+        "max": primaryDbPoolMax,
+      },
+    },
   })
 
   writeYaml(secondaryFileName, map[string]interface{}{
@@ -56,18 +106,52 @@ func setup() {
       "width": secondaryWidth,
       "HEIGHT": secondaryHeight,
     },
+    productionSection: map[string]interface{}{
+      "db": map[string]interface{}{
+        "pool": map[string]interface{}{
+          "max": secondaryDbPoolMax,
+        },
+      },
+    },
   })
 
+  writeJson(jsonFileName, map[string]interface{}{
+    "WIDTH": jsonWidth,
+    "numbers": numbers,
+    "db": map[string]interface{}{
+      "pool": map[string]interface{}{
+        "max": jsonDbPoolMax,
+      },
+    },
+    section: map[string]interface{}{
+      "width": jsonWidth,
+    },
+  })
+
+  err := ioutil.WriteFile(tomlFileName, []byte(tomlContents), 0644)
+
+  if err != nil {
+    panic(err)
+  }
+
   os.Setenv("HERO_NAME", heroName)
   os.Setenv("HEIGHT", fmt.Sprintf("%d", tertiaryHeight))
 
   os.Setenv("IS_AWESOME", fmt.Sprintf("%t", tertiaryIsAwesome))
   os.Setenv("IS_TERRIBLE", fmt.Sprintf("%t", tertiaryIsTerrible))
+
+  os.Setenv(fmt.Sprintf("%sWIDTH", envVarPrefix), fmt.Sprintf("%d", prefixedWidth))
+  os.Setenv(fmt.Sprintf("%sHERO_NAME", envVarPrefix), prefixedName)
+
+  os.Setenv("DB__POOL__MAX", fmt.Sprintf("%d", envDbPoolMax))
+  os.Setenv(fmt.Sprintf("%sDB__POOL__IDLE", envVarPrefix), fmt.Sprintf("%d", envDbPoolIdle))
 }
 
 func teardown() {
   os.Remove(mainFileName)
   os.Remove(secondaryFileName)
+  os.Remove(jsonFileName)
+  os.Remove(tomlFileName)
 }
 
 func TestMain(m *testing.M) {
@@ -753,3 +837,436 @@ func TestGetBoolPUnexistingKey(t *testing.T) {
 
   config.GetBoolP("is_whatever")
 }
+
+func TestGetNested(t *testing.T) {
+  config, _ := config.Load(fmt.Sprintf("test/%s", mainFileName))
+
+  expectedHost := primaryDbHost
+  hostFromConfig, err := config.GetString("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db.host")
+  }
+
+  expectedMax := primaryDbPoolMax
+  maxFromConfig, err := config.GetInt("db.pool.max")
+
+  if maxFromConfig != expectedMax {
+    t.Errorf("Expected %d, got %d", expectedMax, maxFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db.pool.max")
+  }
+
+  pool, err := config.Get("db.pool")
+
+  if _, ok := pool.(map[interface{}]interface{}); ok == false {
+    t.Errorf("Expected db.pool to be a map, got %v", pool)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db.pool")
+  }
+}
+
+func TestGetNestedMissingSegment(t *testing.T) {
+  config, _ := config.Load(fmt.Sprintf("test/%s", mainFileName))
+
+  _, err := config.Get("db.cache.size")
+
+  if err == nil {
+    t.Errorf("Expected not to find key: db.cache.size")
+  } else if strings.Contains(err.Error(), "missing: db.cache") == false {
+    t.Errorf("Expected error to name the missing segment, got %s", err.Error())
+  }
+}
+
+func TestGetNestedNotAMap(t *testing.T) {
+  config, _ := config.Load(fmt.Sprintf("test/%s", mainFileName))
+
+  _, err := config.Get("db.host.name")
+
+  if err == nil {
+    t.Errorf("Expected not to find key: db.host.name")
+  } else if strings.Contains(err.Error(), "db.host is not a map") == false {
+    t.Errorf("Expected error to name the segment that is not a map, got %s", err.Error())
+  }
+}
+
+func TestGetIntPNested(t *testing.T) {
+  config := config.LoadP(fmt.Sprintf("test/%s", mainFileName))
+
+  expectedMax := primaryDbPoolMax
+  maxFromConfig := config.GetIntP("db.pool.max")
+
+  if maxFromConfig != expectedMax {
+    t.Errorf("Expected %d, got %d", expectedMax, maxFromConfig)
+  }
+}
+
+func TestGetPNestedUnexistingKey(t *testing.T) {
+  defer func(){
+    r := recover()
+
+    if r == nil {
+      t.Errorf("Expected to be recovering from a panic here")
+    }
+  }()
+
+  config := config.LoadP(fmt.Sprintf("test/%s", mainFileName))
+  config.GetP("db.pool.min")
+}
+
+func TestMergeNested(t *testing.T) {
+  c1, _ := config.Load(fmt.Sprintf("test/%s", mainFileName))
+  c2, _ := config.LoadSection(fmt.Sprintf("test/%s", secondaryFileName), productionSection)
+
+  config := c1.Merge(c2)
+
+  expectedMax := secondaryDbPoolMax
+  maxFromConfig, err := config.GetInt("db.pool.max")
+
+  if maxFromConfig != expectedMax {
+    t.Errorf("Expected %d, got %d", expectedMax, maxFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db.pool.max")
+  }
+
+  expectedHost := primaryDbHost
+  hostFromConfig, err := config.GetString("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db.host")
+  }
+}
+
+func TestMergeDoesNotShareNestedMaps(t *testing.T) {
+  c1, _ := config.Load(fmt.Sprintf("test/%s", mainFileName))
+  c2, _ := config.LoadSection(fmt.Sprintf("test/%s", secondaryFileName), productionSection)
+
+  merged := c1.Merge(c2)
+
+  pool := merged.GetP("db.pool").(map[interface{}]interface{})
+  pool["max"] = -1
+
+  db := merged.GetP("db").(map[interface{}]interface{})
+  db["host"] = "elsewhere"
+
+  expectedBaseMax := primaryDbPoolMax
+  baseMax := c1.GetIntP("db.pool.max")
+
+  if baseMax != expectedBaseMax {
+    t.Errorf("Expected %d, got %d", expectedBaseMax, baseMax)
+  }
+
+  expectedOverlayMax := secondaryDbPoolMax
+  overlayMax := c2.GetIntP("db.pool.max")
+
+  if overlayMax != expectedOverlayMax {
+    t.Errorf("Expected %d, got %d", expectedOverlayMax, overlayMax)
+  }
+
+  expectedHost := primaryDbHost
+  host := c1.GetStringP("db.host")
+
+  if host != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, host)
+  }
+}
+
+func TestMergeReplacesNonMapValues(t *testing.T) {
+  c1, _ := config.Load(fmt.Sprintf("test/%s", mainFileName))
+  c2 := &config.Config{Data: config.ConfigData{"db": "sqlite"}}
+
+  config := c1.Merge(c2)
+
+  expected := "sqlite"
+  fromConfig, err := config.GetString("db")
+
+  if fromConfig != expected {
+    t.Errorf("Expected %s, got %s", expected, fromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db")
+  }
+}
+
+func TestMergeWithPrefixedEnvVars(t *testing.T) {
+  config, _ := config.Load(fmt.Sprintf("test/%s", mainFileName))
+  config = config.MergeWithPrefixedEnvVars(envVarPrefix)
+
+  expectedWidth := fmt.Sprintf("%d", prefixedWidth)
+  widthFromConfig, err := config.GetString("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %s, got %s", expectedWidth, widthFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: width")
+  }
+
+  expectedHeroName := prefixedName
+  heroNameFromConfig, err := config.GetString("hero_name")
+
+  if heroNameFromConfig != expectedHeroName {
+    t.Errorf("Expected %s, got %s", expectedHeroName, heroNameFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: hero_name")
+  }
+
+  expectedHeight := fmt.Sprintf("%.1f", primaryHeight)
+  heightFromConfig, err := config.GetString("height")
+
+  if heightFromConfig != expectedHeight {
+    t.Errorf("Expected %s, got %s", expectedHeight, heightFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: height")
+  }
+
+  _, err = config.Get("path")
+
+  if err == nil {
+    t.Errorf("Expected not to find key: path")
+  }
+
+  _, err = config.Get(strings.ToLower(fmt.Sprintf("%swidth", envVarPrefix)))
+
+  if err == nil {
+    t.Errorf("Expected prefixed keys not to be present")
+  }
+}
+
+func TestMergeWithEnvVarsNested(t *testing.T) {
+  config, _ := config.Load(fmt.Sprintf("test/%s", mainFileName))
+  config = config.MergeWithEnvVars()
+
+  expectedMax := envDbPoolMax
+  maxFromConfig, err := config.GetInt("db.pool.max")
+
+  if maxFromConfig != expectedMax {
+    t.Errorf("Expected %d, got %d", expectedMax, maxFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db.pool.max")
+  }
+
+  expectedHost := primaryDbHost
+  hostFromConfig, err := config.GetString("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db.host")
+  }
+
+  _, err = config.Get("db__pool__max")
+
+  if err == nil {
+    t.Errorf("Expected not to find key: db__pool__max")
+  }
+}
+
+func TestMergeWithPrefixedEnvVarsNested(t *testing.T) {
+  config, _ := config.Load(fmt.Sprintf("test/%s", mainFileName))
+  config = config.MergeWithPrefixedEnvVars(envVarPrefix)
+
+  expectedIdle := envDbPoolIdle
+  idleFromConfig, err := config.GetInt("db.pool.idle")
+
+  if idleFromConfig != expectedIdle {
+    t.Errorf("Expected %d, got %d", expectedIdle, idleFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db.pool.idle")
+  }
+
+  expectedMax := primaryDbPoolMax
+  maxFromConfig, err := config.GetInt("db.pool.max")
+
+  if maxFromConfig != expectedMax {
+    t.Errorf("Expected %d, got %d", expectedMax, maxFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db.pool.max")
+  }
+}
+
+func TestMergeWithEnvVarsCustomSeparator(t *testing.T) {
+  separator := config.EnvVarSeparator
+  config.EnvVarSeparator = "_"
+
+  defer func() {
+    config.EnvVarSeparator = separator
+  }()
+
+  c, _ := config.Load(fmt.Sprintf("test/%s", mainFileName))
+  c = c.MergeWithPrefixedEnvVars(envVarPrefix)
+
+  expected := prefixedName
+  fromConfig, err := c.GetString("hero.name")
+
+  if fromConfig != expected {
+    t.Errorf("Expected %s, got %s", expected, fromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: hero.name")
+  }
+}
+
+func TestLoadBytes(t *testing.T) {
+  config, err := config.LoadBytes([]byte("WIDTH: 300\ndb:\n  host: example.com\n"))
+
+  if err != nil {
+    t.Errorf("Expected no error, got %s", err.Error())
+  }
+
+  expectedWidth := 300
+  widthFromConfig := config.GetIntP("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %d", expectedWidth, widthFromConfig)
+  }
+
+  expectedHost := "example.com"
+  hostFromConfig := config.GetStringP("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+}
+
+func TestLoadBytesInvalid(t *testing.T) {
+  config, err := config.LoadBytes([]byte("width: [unclosed"))
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  }
+
+  if config != nil {
+    t.Errorf("Expected config to be nil")
+  }
+}
+
+func TestLoadBytesSection(t *testing.T) {
+  config, err := config.LoadBytesSection([]byte("env_vars:\n  HEIGHT: 400\n"), section)
+
+  if err != nil {
+    t.Errorf("Expected no error, got %s", err.Error())
+  }
+
+  expectedHeight := 400
+  heightFromConfig := config.GetIntP("height")
+
+  if heightFromConfig != expectedHeight {
+    t.Errorf("Expected %d, got %d", expectedHeight, heightFromConfig)
+  }
+}
+
+func TestLoadBytesSectionUnexistingSection(t *testing.T) {
+  config, err := config.LoadBytesSection([]byte("width: 300\n"), "whatever")
+
+  if err == nil {
+    t.Errorf("Expected to get an error after trying to load section: whatever")
+  }
+
+  if config != nil {
+    t.Errorf("Expected config to be nil")
+  }
+}
+
+func TestLoadBytesSectionNotAMap(t *testing.T) {
+  config, err := config.LoadBytesSection([]byte("a: 1\n"), "a")
+
+  if err == nil {
+    t.Fatalf("Expected to get an error after trying to load a scalar as a section")
+  }
+
+  if strings.Contains(err.Error(), "Could not read sub-section: a (not a map)") == false {
+    t.Errorf("Expected the error to name the section, got %s", err.Error())
+  }
+
+  if config != nil {
+    t.Errorf("Expected config to be nil")
+  }
+}
+
+func TestLoadBytesSectionNonStringKeys(t *testing.T) {
+  config, err := config.LoadBytesSection([]byte("a:\n  1: x\n"), "a")
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  if config.GetStringP("1") != "x" {
+    t.Errorf("Expected x under 1, got %s", config.GetStringP("1"))
+  }
+}
+
+func TestLoadReader(t *testing.T) {
+  config, err := config.LoadReader(strings.NewReader("width: 300\n"))
+
+  if err != nil {
+    t.Errorf("Expected no error, got %s", err.Error())
+  }
+
+  expectedWidth := 300
+  widthFromConfig := config.GetIntP("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %d", expectedWidth, widthFromConfig)
+  }
+}
+
+func TestLoadReaderSectionP(t *testing.T) {
+  file, err := os.Open(secondaryFileName)
+
+  if err != nil {
+    t.Fatalf("Could not open %s", secondaryFileName)
+  }
+
+  defer file.Close()
+
+  config := config.LoadReaderSectionP(file, section)
+
+  expectedWidth := secondaryWidth
+  widthFromConfig := config.GetIntP("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %d", expectedWidth, widthFromConfig)
+  }
+}
+
+func TestLoadReaderPInvalid(t *testing.T) {
+  defer func(){
+    r := recover()
+
+    if r == nil {
+      t.Errorf("Expected to be recovering from a panic here")
+    }
+  }()
+
+  config.LoadReaderP(strings.NewReader("width: [unclosed"))
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+  "fmt"
+  "testing"
+  "app/config"
+)
+
+type defaultedPool struct {
+  Max int `config:"max" default:"50"`
+  Idle int `config:"idle" default:"2"`
+}
+
+type defaultedSettings struct {
+  Width int `config:"width" default:"100"`
+  Depth int `config:"depth" default:"7"`
+  Name string `config:"name"`
+  Db struct {
+    Host string `config:"host" default:"db.internal"`
+    Pool defaultedPool `config:"pool"`
+  } `config:"db"`
+}
+
+func TestDefaults(t *testing.T) {
+  defaults := config.Defaults(config.ConfigData{"Width": 100, "depth": 7})
+  c := defaults.Merge(config.LoadP(fmt.Sprintf("test/%s", mainFileName)))
+
+  expectedWidth := primaryWidth
+  widthFromConfig, err := c.GetInt("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %d", expectedWidth, widthFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: width")
+  }
+
+  expectedDepth := 7
+  depthFromConfig, err := c.GetInt("depth")
+
+  if depthFromConfig != expectedDepth {
+    t.Errorf("Expected %d, got %d", expectedDepth, depthFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: depth")
+  }
+
+  if c.IsDefault("width") {
+    t.Errorf("Expected width not to come from defaults")
+  }
+
+  if c.IsDefault("depth") == false {
+    t.Errorf("Expected depth to come from defaults")
+  }
+
+  if c.IsDefault("unexisting") {
+    t.Errorf("Expected unexisting key not to come from defaults")
+  }
+}
+
+func TestDefaultsStayUnderneathInAnyMergeOrder(t *testing.T) {
+  defaults := config.Defaults(config.ConfigData{"width": 100})
+  c := config.LoadP(fmt.Sprintf("test/%s", mainFileName)).Merge(defaults)
+
+  expectedWidth := primaryWidth
+  widthFromConfig := c.GetIntP("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %d", expectedWidth, widthFromConfig)
+  }
+
+  c = c.MergeWithEnvVars()
+
+  if c.IsDefault("width") {
+    t.Errorf("Expected width not to come from defaults")
+  }
+}
+
+func TestDefaultsUnderAReplacedSection(t *testing.T) {
+  defaults := config.Defaults(config.ConfigData{"db": map[interface{}]interface{}{"port": 9}, "depth": 7})
+  c := defaults.Merge(config.LoadBytesP([]byte("db: scalar\n")))
+
+  if c.GetStringP("db") != "scalar" {
+    t.Errorf("Expected scalar, got %v", c.GetP("db"))
+  }
+
+  _, err := c.Get("db.port")
+
+  if err == nil {
+    t.Errorf("Expected db.port to be replaced along with the db section")
+  }
+
+  if c.IsDefault("db.port") {
+    t.Errorf("Expected db.port not to come from defaults")
+  }
+
+  var settings struct {
+    Db string
+    Depth int
+  }
+
+  c.UnmarshalP(&settings)
+
+  if settings.Db != "scalar" || settings.Depth != 7 {
+    t.Errorf("Expected Unmarshal to agree with Get, got %+v", settings)
+  }
+}
+
+func TestDefaultsFromStruct(t *testing.T) {
+  defaults, err := config.DefaultsFromStruct(&defaultedSettings{})
+
+  if err != nil {
+    t.Errorf("Expected no error, got %s", err.Error())
+  }
+
+  c := defaults.Merge(config.LoadP(fmt.Sprintf("test/%s", mainFileName)))
+
+  expectedHost := primaryDbHost
+  hostFromConfig := c.GetStringP("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+
+  expectedIdle := 2
+  idleFromConfig := c.GetIntP("db.pool.idle")
+
+  if idleFromConfig != expectedIdle {
+    t.Errorf("Expected %d, got %d", expectedIdle, idleFromConfig)
+  }
+
+  if c.IsDefault("db.pool.idle") == false {
+    t.Errorf("Expected db.pool.idle to come from defaults")
+  }
+
+  if c.IsDefault("db.pool.max") {
+    t.Errorf("Expected db.pool.max not to come from defaults")
+  }
+
+  pool := c.GetP("db.pool").(map[interface{}]interface{})
+
+  if fmt.Sprintf("%v", pool["max"]) != fmt.Sprintf("%d", primaryDbPoolMax) || pool["idle"] != "2" {
+    t.Errorf("Expected defaults to be merged into db.pool, got %v", pool)
+  }
+
+  _, err = c.Get("name")
+
+  if err == nil {
+    t.Errorf("Expected fields without a default not to be set")
+  }
+}
+
+func TestDefaultsUnmarshal(t *testing.T) {
+  c := config.DefaultsFromStructP(defaultedSettings{}).Merge(config.LoadP(fmt.Sprintf("test/%s", mainFileName)))
+  settings := defaultedSettings{}
+
+  c.UnmarshalP(&settings)
+
+  if settings.Width != primaryWidth {
+    t.Errorf("Expected %d, got %d", primaryWidth, settings.Width)
+  }
+
+  if settings.Depth != 7 {
+    t.Errorf("Expected %d, got %d", 7, settings.Depth)
+  }
+
+  if settings.Db.Pool.Max != primaryDbPoolMax {
+    t.Errorf("Expected %d, got %d", primaryDbPoolMax, settings.Db.Pool.Max)
+  }
+
+  if settings.Db.Pool.Idle != 2 {
+    t.Errorf("Expected %d, got %d", 2, settings.Db.Pool.Idle)
+  }
+}
+
+func TestDefaultsFromStructNotAStruct(t *testing.T) {
+  _, err := config.DefaultsFromStruct(42)
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  }
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+  "strings"
+  "testing"
+  "encoding/json"
+  "app/config"
+)
+
+func TestDiff(t *testing.T) {
+  before := config.LoadBytesP([]byte(`
+name: app
+db:
+  host: localhost
+  password: hunter2
+  pool:
+    max: 10
+servers: [a, b, c]
+legacy:
+  enabled: true
+`))
+
+  after := config.Defaults(config.ConfigData{"port": 8080}).Merge(config.LoadBytesP([]byte(`
+name: app
+db:
+  host: db.internal
+  password: correct horse
+  pool:
+    max: 10
+    idle: 3
+servers: [a, x]
+`)))
+
+  changes := config.Diff(before, after)
+
+  expected := strings.Join([]string{
+    `~ db.host: "localhost" -> "db.internal"`,
+    `~ db.password: "******" -> "******"`,
+    `+ db.pool.idle: 3`,
+    `- legacy: {"enabled":true}`,
+    `+ port: 8080`,
+    `~ servers.1: "b" -> "x"`,
+    `- servers.2: "c"`,
+  }, "\n")
+
+  if changes.String() != expected {
+    t.Errorf("Expected\n%s\ngot\n%s", expected, changes.String())
+  }
+
+  if changes[2].Kind != config.Added || changes[2].Path != "db.pool.idle" || changes[2].New != 3 || changes[2].Old != nil {
+    t.Errorf("Expected db.pool.idle to be added, got %v", changes[2])
+  }
+}
+
+func TestDiffJson(t *testing.T) {
+  before := config.LoadBytesP([]byte("db:\n  dsn: postgres://user:pass@db/app\n  host: a\n"))
+  after := config.LoadBytesP([]byte("db:\n  dsn: postgres://user:other@db/app\n  host: a\n"))
+  after.MarkSecret("db.dsn")
+
+  contents, err := json.Marshal(config.Diff(before, after))
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  decoded := []map[string]interface{}{}
+  json.Unmarshal(contents, &decoded)
+
+  if len(decoded) != 1 || decoded[0]["kind"] != "changed" || decoded[0]["path"] != "db.dsn" || decoded[0]["old"] != config.Redacted || decoded[0]["new"] != config.Redacted {
+    t.Errorf("Expected one redacted change, got %s", contents)
+  }
+}
+
+func TestDiffSameConfig(t *testing.T) {
+  c := config.LoadBytesP([]byte("a: 1\nb: [1, 2]\n"))
+
+  changes := config.Diff(c, c)
+
+  if len(changes) != 0 {
+    t.Errorf("Expected no changes, got %s", changes)
+  }
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+  "os"
+  "fmt"
+  "strings"
+  "testing"
+  "testing/fstest"
+  "io/ioutil"
+  "app/config"
+)
+
+var dotenvFileName string = "./dotenvFile.env"
+var dotenvContents string = `# Local development settings
+WIDTH=1000
+export HERO_NAME="Jon Snow"
+GREETING="Hello\nWorld \"quoted\" \$HOME"
+LITERAL='no \n escapes here'
+INLINE=value # a comment
+HASH=pass#word
+EMPTY=
+DB__POOL__MAX=70
+CERTIFICATE="-----BEGIN-----
+line two
+-----END-----"
+DOTENV_ONLY_VARIABLE=from file
+`
+
+func writeDotenv(t *testing.T, contents string) func() {
+  err := ioutil.WriteFile(dotenvFileName, []byte(contents), 0644)
+
+  if err != nil {
+    t.Fatalf("Could not write %s", dotenvFileName)
+  }
+
+  return func() {
+    os.Remove(dotenvFileName)
+  }
+}
+
+func TestLoadDotenv(t *testing.T) {
+  defer writeDotenv(t, dotenvContents)()
+
+  config, err := config.Load(fmt.Sprintf("test/%s", dotenvFileName))
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  expected := map[string]string{
+    "width": "1000",
+    "hero_name": "Jon Snow",
+    "greeting": "Hello\nWorld \"quoted\" $HOME",
+    "literal": "no \\n escapes here",
+    "inline": "value",
+    "hash": "pass#word",
+    "empty": "",
+    "db.pool.max": "70",
+    "certificate": "-----BEGIN-----\nline two\n-----END-----",
+  }
+
+  for key, expectedValue := range expected {
+    value, err := config.GetString(key)
+
+    if value != expectedValue {
+      t.Errorf("Expected %q under %s, got %q", expectedValue, key, value)
+    }
+
+    if err != nil {
+      t.Errorf("Expected to find key: %s", key)
+    }
+  }
+
+  if _, found := os.LookupEnv("DOTENV_ONLY_VARIABLE"); found {
+    t.Errorf("Expected loading a .env file not to touch the environment")
+  }
+}
+
+func TestLoadDotenvKeepsQuotedWhitespace(t *testing.T) {
+  contents := "SPACED=\"line1   \nline2\"\n  PADDED='  both  '  \nexport  TABBED=\"a\t\"\n"
+  c, err := config.LoadBytesFormat([]byte(contents), config.Dotenv)
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  expected := map[string]string{"spaced": "line1   \nline2", "padded": "  both  ", "tabbed": "a\t"}
+
+  for key, expectedValue := range expected {
+    if value := c.GetStringP(key); value != expectedValue {
+      t.Errorf("Expected %q under %s, got %q", expectedValue, key, value)
+    }
+  }
+}
+
+func TestMergeWithDotenv(t *testing.T) {
+  defer writeDotenv(t, dotenvContents)()
+
+  c1 := config.LoadP(fmt.Sprintf("test/%s", mainFileName))
+  c2 := config.LoadP(fmt.Sprintf("test/%s", dotenvFileName))
+
+  config := c1.Merge(c2)
+
+  expectedMax := 70
+  maxFromConfig := config.GetIntP("db.pool.max")
+
+  if maxFromConfig != expectedMax {
+    t.Errorf("Expected %d, got %d", expectedMax, maxFromConfig)
+  }
+
+  expectedHost := primaryDbHost
+  hostFromConfig := config.GetStringP("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+}
+
+func TestLoadDotenvErrors(t *testing.T) {
+  invalid := map[string]string{
+    "NO_SEPARATOR": "line 1",
+    "1NAME=x": "invalid name",
+    "A=\"unterminated": "unterminated",
+    "A=\"x\" trailing": "unexpected",
+  }
+
+  for contents, message := range invalid {
+    _, err := config.LoadBytesFormat([]byte(contents), config.Dotenv)
+
+    if err == nil {
+      t.Errorf("Expected to see error for %q", contents)
+    } else if strings.Contains(err.Error(), message) == false {
+      t.Errorf("Expected error for %q to contain %q, got %s", contents, message, err.Error())
+    }
+  }
+}
+
+func TestExportDotenv(t *testing.T) {
+  defer writeDotenv(t, "DOTENV_EXPORTED=from file\nHERO_NAME=Arya\n")()
+  defer os.Unsetenv("DOTENV_EXPORTED")
+
+  err := config.ExportDotenv(fmt.Sprintf("test/%s", dotenvFileName), false)
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  if os.Getenv("DOTENV_EXPORTED") != "from file" {
+    t.Errorf("Expected %s, got %s", "from file", os.Getenv("DOTENV_EXPORTED"))
+  }
+
+  if os.Getenv("HERO_NAME") != heroName {
+    t.Errorf("Expected existing variable to be kept, got %s", os.Getenv("HERO_NAME"))
+  }
+
+  config.ExportDotenvP(fmt.Sprintf("test/%s", dotenvFileName), true)
+  defer os.Setenv("HERO_NAME", heroName)
+
+  if os.Getenv("HERO_NAME") != "Arya" {
+    t.Errorf("Expected existing variable to be overwritten, got %s", os.Getenv("HERO_NAME"))
+  }
+}
+
+func TestLoadDotenvByName(t *testing.T) {
+  fsys := fstest.MapFS{
+    ".env": &fstest.MapFile{Data: []byte("DB__HOST=from-env\n")},
+    "config/.env.local": &fstest.MapFile{Data: []byte("DB__HOST=from-local\n")},
+    ".env.production": &fstest.MapFile{Data: []byte("DB__HOST=from-production\n")},
+  }
+
+  expected := map[string]string{
+    ".env": "from-env",
+    "config/.env.local": "from-local",
+    ".env.production": "from-production",
+  }
+
+  for path, expectedHost := range expected {
+    c, err := config.LoadFS(fsys, path)
+
+    if err != nil {
+      t.Errorf("Expected %s to load as dotenv, got %s", path, err.Error())
+      continue
+    }
+
+    if host, _ := c.GetString("db.host"); host != expectedHost {
+      t.Errorf("Expected %s from %s, got %s", expectedHost, path, host)
+    }
+  }
+}
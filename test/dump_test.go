@@ -0,0 +1,136 @@
+package main
+
+import (
+  "fmt"
+  "strings"
+  "testing"
+  "encoding/json"
+  "gopkg.in/yaml.v2"
+  "app/config"
+)
+
+func secretConfig() *config.Config {
+  return config.LoadBytesP([]byte(`
+name: app
+db:
+  host: localhost
+  password: hunter2
+  credentials:
+    user: admin
+    key: abc
+api_token: t0k3n
+servers:
+  - host: a
+    Secret: xyz
+empty_password:
+`))
+}
+
+func TestDumpYaml(t *testing.T) {
+  c := secretConfig()
+  c.MarkSecret("db.credentials")
+
+  contents, err := c.Dump(config.YAML)
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  dumped := config.LoadBytesP(contents)
+
+  expected := map[string]string{
+    "name": "app",
+    "db.host": "localhost",
+    "db.password": config.Redacted,
+    "db.credentials": config.Redacted,
+    "api_token": config.Redacted,
+    "servers.0.host": "a",
+    "servers.0.Secret": config.Redacted,
+    "empty_password": "",
+  }
+
+  for key, expectedValue := range expected {
+    value, err := dumped.GetString(key)
+
+    if value != expectedValue {
+      t.Errorf("Expected %q under %s, got %q", expectedValue, key, value)
+    }
+
+    if err != nil {
+      t.Errorf("Expected to find key: %s", key)
+    }
+  }
+
+  for _, secret := range []string{"hunter2", "t0k3n", "xyz", "admin"} {
+    if strings.Contains(string(contents), secret) {
+      t.Errorf("Expected %s not to be dumped, got %s", secret, contents)
+    }
+  }
+
+  if c.GetStringP("db.password") != "hunter2" {
+    t.Errorf("Expected dumping to leave the config alone")
+  }
+}
+
+func TestDumpJson(t *testing.T) {
+  c := config.Defaults(config.ConfigData{"port": 8080}).Merge(secretConfig())
+  contents := c.DumpP(config.JSON)
+  dumped := map[string]interface{}{}
+
+  err := json.Unmarshal(contents, &dumped)
+
+  if err != nil {
+    t.Fatalf("Expected valid json, got %s", contents)
+  }
+
+  if dumped["port"] != float64(8080) {
+    t.Errorf("Expected defaults to be dumped, got %v", dumped["port"])
+  }
+
+  db := dumped["db"].(map[string]interface{})
+
+  if db["password"] != config.Redacted || db["host"] != "localhost" {
+    t.Errorf("Expected db.password to be redacted, got %v", db)
+  }
+}
+
+func TestDumpUnsupportedFormat(t *testing.T) {
+  _, err := secretConfig().Dump(config.TOML)
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  }
+}
+
+func TestSecretsSurviveMerge(t *testing.T) {
+  c := secretConfig()
+  c.MarkSecret("name")
+
+  merged := config.Defaults(config.ConfigData{}).Merge(c).MergeWithEnvVars()
+
+  if merged.IsSecret("name") == false {
+    t.Errorf("Expected name to stay secret after merging")
+  }
+
+  if merged.IsSecret("db.host") {
+    t.Errorf("Expected db.host not to be secret")
+  }
+}
+
+func TestPrintingIsRedacted(t *testing.T) {
+  c := secretConfig()
+
+  for _, format := range []string{"%v", "%+v", "%s", "%#v"} {
+    printed := fmt.Sprintf(format, c)
+
+    if strings.Contains(printed, "hunter2") || strings.Contains(printed, config.Redacted) == false {
+      t.Errorf("Expected %s to redact secrets, got %s", format, printed)
+    }
+  }
+
+  printed := map[string]interface{}{}
+
+  if err := yaml.Unmarshal([]byte(c.String()), &printed); err != nil || printed["name"] != "app" {
+    t.Errorf("Expected String to be yaml, got %s", c.String())
+  }
+}
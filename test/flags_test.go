@@ -0,0 +1,116 @@
+package main
+
+import (
+  "fmt"
+  "flag"
+  "time"
+  "testing"
+  "app/config"
+)
+
+func TestFromFlags(t *testing.T) {
+  fs := flag.NewFlagSet("app", flag.ContinueOnError)
+  fs.Int("width", 100, "")
+  fs.Float64("height", 1.5, "")
+  fs.Bool("is_awesome", false, "")
+  fs.Duration("timeout", time.Second, "")
+  fs.Int("db.pool.max", 5, "")
+  fs.String("name", "default", "")
+
+  err := fs.Parse([]string{"--width=300", "-is_awesome", "--timeout", "1m", "--db.pool.max=80"})
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  flags := config.FromFlags(fs)
+
+  width, _ := flags.Get("width")
+
+  if width != 300 {
+    t.Errorf("Expected %d, got %v (%T)", 300, width, width)
+  }
+
+  timeout, _ := flags.Get("timeout")
+
+  if timeout != time.Minute {
+    t.Errorf("Expected %v, got %v (%T)", time.Minute, timeout, timeout)
+  }
+
+  _, getErr := flags.Get("height")
+
+  if getErr == nil {
+    t.Errorf("Expected flags that were not passed to be left out")
+  }
+
+  _, getErr = flags.Get("name")
+
+  if getErr == nil {
+    t.Errorf("Expected flags that were not passed to be left out")
+  }
+
+  config := config.LoadP(fmt.Sprintf("test/%s", mainFileName)).MergeWithEnvVars().Merge(flags)
+
+  expectedWidth := 300
+  widthFromConfig := config.GetIntP("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %d", expectedWidth, widthFromConfig)
+  }
+
+  expectedHeight := tertiaryHeight
+  heightFromConfig := config.GetIntP("height")
+
+  if heightFromConfig != expectedHeight {
+    t.Errorf("Expected %d, got %d", expectedHeight, heightFromConfig)
+  }
+
+  expectedIsAwesome := true
+  isAwesomeFromConfig := config.GetBoolP("is_awesome")
+
+  if isAwesomeFromConfig != expectedIsAwesome {
+    t.Errorf("Expected %t, got %t", expectedIsAwesome, isAwesomeFromConfig)
+  }
+
+  expectedMax := 80
+  maxFromConfig := config.GetIntP("db.pool.max")
+
+  if maxFromConfig != expectedMax {
+    t.Errorf("Expected %d, got %d", expectedMax, maxFromConfig)
+  }
+
+  expectedHost := primaryDbHost
+  hostFromConfig := config.GetStringP("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+}
+
+type plainValue struct {
+  value string
+}
+
+func (v *plainValue) String() string {
+  return v.value
+}
+
+func (v *plainValue) Set(value string) error {
+  v.value = value
+  return nil
+}
+
+func TestFromFlagsWithoutGetter(t *testing.T) {
+  fs := flag.NewFlagSet("app", flag.ContinueOnError)
+  fs.Var(&plainValue{}, "mode", "")
+  fs.Parse([]string{"--mode", "fast"})
+
+  flags := config.FromFlags(fs)
+
+  expected := "fast"
+  fromConfig := flags.GetStringP("mode")
+
+  if fromConfig != expected {
+    t.Errorf("Expected %s, got %s", expected, fromConfig)
+  }
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+  "fmt"
+  "time"
+  "testing"
+  "app/config"
+)
+
+func TestLoadJson(t *testing.T) {
+  config, err := config.Load(fmt.Sprintf("test/%s", jsonFileName))
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  expectedWidth := jsonWidth
+  widthFromConfig, err := config.Get("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %v (%T)", expectedWidth, widthFromConfig, widthFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: width")
+  }
+
+  pool, err := config.Get("db.pool")
+
+  if _, ok := pool.(map[interface{}]interface{}); ok == false {
+    t.Errorf("Expected db.pool to be a map[interface{}]interface{}, got %T", pool)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db.pool")
+  }
+
+  numbersFromConfig, err := config.Get("numbers")
+
+  if _, ok := numbersFromConfig.([]interface{}); ok == false {
+    t.Errorf("Expected numbers to be a []interface{}, got %T", numbersFromConfig)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: numbers")
+  }
+}
+
+func TestLoadSectionJson(t *testing.T) {
+  config := config.LoadSectionP(fmt.Sprintf("test/%s", jsonFileName), section)
+
+  expectedWidth := jsonWidth
+  widthFromConfig := config.GetIntP("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %d", expectedWidth, widthFromConfig)
+  }
+}
+
+func TestMergeYamlWithJson(t *testing.T) {
+  c1 := config.LoadP(fmt.Sprintf("test/%s", mainFileName))
+  c2 := config.LoadP(fmt.Sprintf("test/%s", jsonFileName))
+
+  config := c1.Merge(c2)
+
+  expectedMax := jsonDbPoolMax
+  maxFromConfig := config.GetIntP("db.pool.max")
+
+  if maxFromConfig != expectedMax {
+    t.Errorf("Expected %d, got %d", expectedMax, maxFromConfig)
+  }
+
+  expectedHost := primaryDbHost
+  hostFromConfig := config.GetStringP("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+}
+
+func TestLoadFormat(t *testing.T) {
+  config, err := config.LoadFormat(fmt.Sprintf("test/%s", jsonFileName), config.JSON)
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  expectedWidth := jsonWidth
+  widthFromConfig := config.GetIntP("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %d", expectedWidth, widthFromConfig)
+  }
+}
+
+func TestLoadBytesFormatJson(t *testing.T) {
+  config := config.LoadBytesFormatP([]byte(`{"Width": 300, "height": 200.5, "db": {"host": "example.com"}}`), config.JSON)
+
+  expectedWidth := 300
+  widthFromConfig, _ := config.Get("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %v (%T)", expectedWidth, widthFromConfig, widthFromConfig)
+  }
+
+  expectedHeight := 200.5
+  heightFromConfig, _ := config.Get("height")
+
+  if heightFromConfig != expectedHeight {
+    t.Errorf("Expected %f, got %v (%T)", expectedHeight, heightFromConfig, heightFromConfig)
+  }
+
+  expectedHost := "example.com"
+  hostFromConfig := config.GetStringP("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+}
+
+func TestLoadBytesFormatInvalidJson(t *testing.T) {
+  config, err := config.LoadBytesFormat([]byte(`{"width": `), config.JSON)
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  }
+
+  if config != nil {
+    t.Errorf("Expected config to be nil")
+  }
+}
+
+func TestLoadBytesSectionFormatJson(t *testing.T) {
+  config := config.LoadBytesSectionFormatP([]byte(`{"env_vars": {"HEIGHT": 400}}`), section, config.JSON)
+
+  expectedHeight := 400
+  heightFromConfig := config.GetIntP("height")
+
+  if heightFromConfig != expectedHeight {
+    t.Errorf("Expected %d, got %d", expectedHeight, heightFromConfig)
+  }
+}
+
+func TestLoadBytesFormatUnsupported(t *testing.T) {
+  config, err := config.LoadBytesFormat([]byte("width: 300"), config.Format("xml"))
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  }
+
+  if config != nil {
+    t.Errorf("Expected config to be nil")
+  }
+}
+
+func TestLoadToml(t *testing.T) {
+  config, err := config.Load(fmt.Sprintf("test/%s", tomlFileName))
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  expectedWidth := 900
+  widthFromConfig, _ := config.Get("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %v (%T)", expectedWidth, widthFromConfig, widthFromConfig)
+  }
+
+  pool, err := config.Get("db.pool")
+
+  if _, ok := pool.(map[interface{}]interface{}); ok == false {
+    t.Errorf("Expected db.pool to be a map[interface{}]interface{}, got %T", pool)
+  }
+
+  if err != nil {
+    t.Errorf("Expected to find key: db.pool")
+  }
+
+  expectedReleased := time.Date(2019, 2, 1, 10, 0, 0, 0, time.UTC)
+  releasedFromConfig, _ := config.Get("released")
+
+  if released, ok := releasedFromConfig.(time.Time); ok == false || released.Equal(expectedReleased) == false {
+    t.Errorf("Expected %v, got %v (%T)", expectedReleased, releasedFromConfig, releasedFromConfig)
+  }
+
+  expectedReleasedString := "2019-02-01T10:00:00Z"
+  releasedString := config.GetStringP("released")
+
+  if releasedString != expectedReleasedString {
+    t.Errorf("Expected %s, got %s", expectedReleasedString, releasedString)
+  }
+
+  servers := config.GetP("servers")
+  serverList, ok := servers.([]interface{})
+
+  if ok == false || len(serverList) != 2 {
+    t.Fatalf("Expected servers to be a []interface{} of two, got %v (%T)", servers, servers)
+  }
+
+  if server, ok := serverList[1].(map[interface{}]interface{}); ok == false || server["host"] != "beta" {
+    t.Errorf("Expected the second server to be a map with host beta, got %v", serverList[1])
+  }
+}
+
+func TestLoadSectionToml(t *testing.T) {
+  config := config.LoadSectionP(fmt.Sprintf("test/%s", tomlFileName), section)
+
+  expectedHeight := 950
+  heightFromConfig := config.GetIntP("height")
+
+  if heightFromConfig != expectedHeight {
+    t.Errorf("Expected %d, got %d", expectedHeight, heightFromConfig)
+  }
+}
+
+func TestMergeYamlWithToml(t *testing.T) {
+  c1 := config.LoadP(fmt.Sprintf("test/%s", mainFileName))
+  c2 := config.LoadP(fmt.Sprintf("test/%s", tomlFileName))
+
+  config := c1.Merge(c2)
+
+  expectedMax := 60
+  maxFromConfig := config.GetIntP("db.pool.max")
+
+  if maxFromConfig != expectedMax {
+    t.Errorf("Expected %d, got %d", expectedMax, maxFromConfig)
+  }
+
+  expectedHost := primaryDbHost
+  hostFromConfig := config.GetStringP("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+
+  expectedHeight := primaryHeight
+  heightFromConfig := config.GetFloatP("height")
+
+  if heightFromConfig != expectedHeight {
+    t.Errorf("Expected %f, got %f", expectedHeight, heightFromConfig)
+  }
+}
+
+func TestUnmarshalToml(t *testing.T) {
+  type settings struct {
+    Width int `config:"width"`
+    Released time.Time `config:"released"`
+    ReleasedString string `config:"released"`
+    Servers []struct {
+      Host string `config:"host"`
+    } `config:"servers"`
+  }
+
+  config := config.LoadP(fmt.Sprintf("test/%s", tomlFileName))
+  s := settings{}
+
+  config.UnmarshalP(&s)
+
+  if s.Width != 900 {
+    t.Errorf("Expected %d, got %d", 900, s.Width)
+  }
+
+  if s.Released.Equal(time.Date(2019, 2, 1, 10, 0, 0, 0, time.UTC)) == false {
+    t.Errorf("Expected 2019-02-01T10:00:00Z, got %v", s.Released)
+  }
+
+  if s.ReleasedString != "2019-02-01T10:00:00Z" {
+    t.Errorf("Expected 2019-02-01T10:00:00Z, got %s", s.ReleasedString)
+  }
+
+  if len(s.Servers) != 2 || s.Servers[0].Host != "alpha" {
+    t.Errorf("Expected two servers, got %v", s.Servers)
+  }
+}
+
+func TestLoadBytesFormatInvalidToml(t *testing.T) {
+  config, err := config.LoadBytesFormat([]byte("width = "), config.TOML)
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  }
+
+  if config != nil {
+    t.Errorf("Expected config to be nil")
+  }
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+  "os"
+  "embed"
+  "testing"
+  "testing/fstest"
+  "app/config"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+func TestLoadFSEmbedded(t *testing.T) {
+  config, err := config.LoadFS(fixtures, "fixtures/embedded.yaml")
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  expectedWidth := 500
+  widthFromConfig := config.GetIntP("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %d", expectedWidth, widthFromConfig)
+  }
+
+  expectedHost := "embedded.example.com"
+  hostFromConfig := config.GetStringP("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+}
+
+func TestLoadSectionFSEmbedded(t *testing.T) {
+  config := config.LoadSectionFSP(fixtures, "fixtures/embedded.yaml", section)
+
+  expectedHeight := 700
+  heightFromConfig := config.GetIntP("height")
+
+  if heightFromConfig != expectedHeight {
+    t.Errorf("Expected %d, got %d", expectedHeight, heightFromConfig)
+  }
+}
+
+func TestLoadFSDir(t *testing.T) {
+  config := config.LoadFSP(os.DirFS("."), mainFileName[2:])
+
+  expectedWidth := primaryWidth
+  widthFromConfig := config.GetIntP("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %d", expectedWidth, widthFromConfig)
+  }
+}
+
+func TestLoadFSMap(t *testing.T) {
+  fsys := fstest.MapFS{
+    "config/app.yaml": &fstest.MapFile{Data: []byte("Width: 42\n")},
+  }
+
+  config := config.LoadFSP(fsys, "config/app.yaml")
+
+  expectedWidth := 42
+  widthFromConfig := config.GetIntP("width")
+
+  if widthFromConfig != expectedWidth {
+    t.Errorf("Expected %d, got %d", expectedWidth, widthFromConfig)
+  }
+}
+
+func TestLoadFSUnexistingFile(t *testing.T) {
+  config, err := config.LoadFS(fstest.MapFS{}, "whatever.yaml")
+
+  if err == nil {
+    t.Errorf("Expected to get an error after trying to load: whatever.yaml")
+  }
+
+  if config != nil {
+    t.Errorf("Expected config to be nil")
+  }
+}
+
+func TestLoadSectionFSUnexistingSection(t *testing.T) {
+  config, err := config.LoadSectionFS(fixtures, "fixtures/embedded.yaml", "whatever")
+
+  if err == nil {
+    t.Errorf("Expected to get an error after trying to load section: whatever")
+  }
+
+  if config != nil {
+    t.Errorf("Expected config to be nil")
+  }
+}
+
+func TestLoadSectionFSPUnexistingFile(t *testing.T) {
+  defer func(){
+    r := recover()
+
+    if r == nil {
+      t.Errorf("Expected to be recovering from a panic here")
+    }
+  }()
+
+  config.LoadSectionFSP(fstest.MapFS{}, "whatever.yaml", "whatever")
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+  "fmt"
+  "time"
+  "reflect"
+  "strings"
+  "testing"
+  "app/config"
+)
+
+type logLevel int
+
+const (
+  debugLevel logLevel = iota
+  infoLevel
+  errorLevel
+)
+
+func parseLogLevel(value interface{}) (logLevel, error) {
+  switch strings.ToLower(fmt.Sprintf("%v", value)) {
+    case "debug":
+      return debugLevel, nil
+    case "info":
+      return infoLevel, nil
+    case "error":
+      return errorLevel, nil
+  }
+
+  return debugLevel, fmt.Errorf("expected debug, info or error")
+}
+
+func init() {
+  config.RegisterConverter(parseLogLevel)
+}
+
+var genericContents = []byte(`
+width: 300
+ratio: 0.5
+enabled: true
+timeout: 30s
+hosts: [a, b]
+level: ERROR
+bad_level: loud
+db:
+  host: localhost
+  port: 5432
+services:
+  - name: api
+    level: info
+`)
+
+func TestGenericGet(t *testing.T) {
+  c := config.LoadBytesP(genericContents)
+
+  if width := config.MustGet[int](c, "width"); width != 300 {
+    t.Errorf("Expected 300, got %d", width)
+  }
+
+  if ratio := config.MustGet[float64](c, "ratio"); ratio != 0.5 {
+    t.Errorf("Expected 0.5, got %f", ratio)
+  }
+
+  if config.MustGet[bool](c, "enabled") == false {
+    t.Errorf("Expected enabled to be true")
+  }
+
+  if timeout := config.MustGet[time.Duration](c, "timeout"); timeout != 30 * time.Second {
+    t.Errorf("Expected 30s, got %s", timeout)
+  }
+
+  if hosts := config.MustGet[[]string](c, "hosts"); reflect.DeepEqual(hosts, []string{"a", "b"}) == false {
+    t.Errorf("Expected [a b], got %v", hosts)
+  }
+
+  if port := config.MustGet[uint16](c, "db.port"); port != 5432 {
+    t.Errorf("Expected 5432, got %d", port)
+  }
+
+  type db struct {
+    Host string `config:"host"`
+    Port int `config:"port"`
+  }
+
+  if section := config.MustGet[db](c, "db"); section.Host != "localhost" || section.Port != 5432 {
+    t.Errorf("Expected the db section, got %v", section)
+  }
+}
+
+func TestGenericGetWithConverter(t *testing.T) {
+  c := config.LoadBytesP(genericContents)
+
+  level, err := config.Get[logLevel](c, "level")
+
+  if err != nil || level != errorLevel {
+    t.Errorf("Expected the registered converter to be used, got %v, %v", level, err)
+  }
+
+  _, err = config.Get[logLevel](c, "bad_level")
+
+  if err == nil || strings.Contains(err.Error(), "Could not read key: bad_level") == false || strings.Contains(err.Error(), "expected debug, info or error") == false {
+    t.Errorf("Expected the converter error to name the key, got %v", err)
+  }
+
+  settings := struct {
+    Level logLevel `config:"level"`
+    Services []struct {
+      Level logLevel `config:"level"`
+    } `config:"services"`
+  }{}
+
+  c.UnmarshalP(&settings)
+
+  if settings.Level != errorLevel || settings.Services[0].Level != infoLevel {
+    t.Errorf("Expected Unmarshal to use the converter, got %v", settings)
+  }
+
+  bad := struct {
+    Level logLevel `config:"bad_level"`
+  }{}
+
+  unmarshalErr := c.Unmarshal(&bad)
+
+  if unmarshalErr == nil || strings.Contains(unmarshalErr.Error(), "bad_level: cannot convert \"loud\"") == false {
+    t.Errorf("Expected Unmarshal to report the converter error, got %v", unmarshalErr)
+  }
+}
+
+func TestGenericGetErrors(t *testing.T) {
+  c := config.LoadBytesP(genericContents)
+
+  _, err := config.Get[int](c, "unexisting")
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  }
+
+  _, err = config.Get[uint8](c, "db.port")
+
+  if err == nil || strings.Contains(err.Error(), "Could not read key: db.port") == false {
+    t.Errorf("Expected the overflow to name the key, got %v", err)
+  }
+
+  defer func() {
+    if recover() == nil {
+      t.Errorf("Expected MustGet to panic")
+    }
+  }()
+
+  config.MustGet[time.Duration](c, "hosts")
+}
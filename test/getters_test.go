@@ -0,0 +1,174 @@
+package main
+
+import (
+  "os"
+  "time"
+  "strings"
+  "testing"
+  "app/config"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+var typedContents = []byte(`
+timeout: 30s
+retry: 1500000000
+started_at: 2021-03-04T05:06:07.5Z
+birthday: 2021-03-04
+cache: 512MiB
+upload: 1.5GB
+block: 4096
+endpoint: https://api.example.com:8443/v1?debug=true
+relative: /v1/users
+bind: 10.0.0.1
+bind6: "::1"
+network: 10.1.2.3/8
+`)
+
+func TestTypedGetters(t *testing.T) {
+  c := config.LoadBytesP(typedContents)
+
+  if c.GetDurationP("timeout") != 30 * time.Second {
+    t.Errorf("Expected 30s, got %s", c.GetDurationP("timeout"))
+  }
+
+  if c.GetDurationP("retry") != 1500 * time.Millisecond {
+    t.Errorf("Expected plain numbers to be nanoseconds, got %s", c.GetDurationP("retry"))
+  }
+
+  expectedTime := time.Date(2021, 3, 4, 5, 6, 7, 500000000, time.UTC)
+
+  if c.GetTimeP("started_at").Equal(expectedTime) == false {
+    t.Errorf("Expected %s, got %s", expectedTime, c.GetTimeP("started_at"))
+  }
+
+  if c.GetTimeP("birthday").Equal(time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)) == false {
+    t.Errorf("Expected a plain date, got %s", c.GetTimeP("birthday"))
+  }
+
+  sizes := map[string]int64{"cache": 512 << 20, "upload": 1500000000, "block": 4096}
+
+  for key, expected := range sizes {
+    size := c.GetByteSizeP(key)
+
+    if size != expected {
+      t.Errorf("Expected %d under %s, got %d", expected, key, size)
+    }
+  }
+
+  endpoint := c.GetURLP("endpoint")
+
+  if endpoint.Hostname() != "api.example.com" || endpoint.Port() != "8443" || endpoint.Query().Get("debug") != "true" {
+    t.Errorf("Expected the endpoint to be parsed, got %s", endpoint)
+  }
+
+  if c.GetIPP("bind").String() != "10.0.0.1" || c.GetIPP("bind6").String() != "::1" {
+    t.Errorf("Expected ips to be parsed, got %s and %s", c.GetIPP("bind"), c.GetIPP("bind6"))
+  }
+
+  if c.GetIPNetP("network").String() != "10.0.0.0/8" {
+    t.Errorf("Expected the network to be masked, got %s", c.GetIPNetP("network"))
+  }
+}
+
+func TestTypedGettersFromEnvVars(t *testing.T) {
+  os.Setenv("TYPED_TIMEOUT", "2m")
+  os.Setenv("TYPED_CACHE", "1 GiB")
+  os.Setenv("TYPED_BIND", "192.168.0.1")
+  defer os.Unsetenv("TYPED_TIMEOUT")
+  defer os.Unsetenv("TYPED_CACHE")
+  defer os.Unsetenv("TYPED_BIND")
+
+  c := config.LoadBytesP(typedContents).MergeWithPrefixedEnvVars("TYPED_")
+
+  if c.GetDurationP("timeout") != 2 * time.Minute {
+    t.Errorf("Expected 2m, got %s", c.GetDurationP("timeout"))
+  }
+
+  if c.GetByteSizeP("cache") != 1 << 30 {
+    t.Errorf("Expected 1GiB, got %d", c.GetByteSizeP("cache"))
+  }
+
+  if c.GetIPP("bind").String() != "192.168.0.1" {
+    t.Errorf("Expected 192.168.0.1, got %s", c.GetIPP("bind"))
+  }
+}
+
+func TestTypedGettersErrors(t *testing.T) {
+  c := config.LoadBytesP(typedContents)
+
+  checks := map[string]func() *errtrace.Error{
+    "endpoint": func() *errtrace.Error { _, err := c.GetDuration("endpoint"); return err },
+    "timeout": func() *errtrace.Error { _, err := c.GetTime("timeout"); return err },
+    "bind": func() *errtrace.Error { _, err := c.GetByteSize("bind"); return err },
+    "relative": func() *errtrace.Error { _, err := c.GetURL("relative"); return err },
+    "cache": func() *errtrace.Error { _, err := c.GetIP("cache"); return err },
+    "bind6": func() *errtrace.Error { _, err := c.GetIPNet("bind6"); return err },
+  }
+
+  for key, check := range checks {
+    err := check()
+
+    if err == nil {
+      t.Errorf("Expected to see error for %s", key)
+    } else if strings.Contains(err.Error(), "Could not read key: " + key) == false {
+      t.Errorf("Expected the error to name %s, got %s", key, err.Error())
+    }
+  }
+
+  _, err := c.GetByteSize("upload_limit")
+
+  if err == nil {
+    t.Errorf("Expected to see error for a missing key")
+  }
+
+  c.Set("upload_limit", "10XB")
+  _, err = c.GetByteSize("upload_limit")
+
+  if err == nil || strings.Contains(err.Error(), `unknown unit "XB"`) == false {
+    t.Errorf("Expected the unit to be reported, got %v", err)
+  }
+}
+
+func TestByteSizeLimit(t *testing.T) {
+  c := config.LoadBytesP([]byte("largest: 8191PiB\ntoo_large: 8192PiB\n"))
+
+  if c.GetByteSizeP("largest") != 8191 << 50 {
+    t.Errorf("Expected 8191PiB, got %d", c.GetByteSizeP("largest"))
+  }
+
+  _, err := c.GetByteSize("too_large")
+
+  if err == nil || strings.Contains(err.Error(), "too large") == false {
+    t.Errorf("Expected 8192PiB to be too large, got %v", err)
+  }
+}
+
+func TestNativeNumbersInExponentForm(t *testing.T) {
+  c := config.LoadBytesP([]byte("size: 1e6\nlarge: 2.5e9\nnegative: -1\ntimeout: 1.5e9\n"))
+
+  if c.GetByteSizeP("size") != 1000000 {
+    t.Errorf("Expected 1e6 bytes, got %d", c.GetByteSizeP("size"))
+  }
+
+  if c.GetByteSizeP("large") != 2500000000 {
+    t.Errorf("Expected 2.5e9 bytes, got %d", c.GetByteSizeP("large"))
+  }
+
+  if _, err := c.GetByteSize("negative"); err == nil {
+    t.Errorf("Expected a negative size to be an error")
+  }
+
+  if c.GetDurationP("timeout") != 1500 * time.Millisecond {
+    t.Errorf("Expected 1.5e9 nanoseconds, got %s", c.GetDurationP("timeout"))
+  }
+
+  j, err := config.LoadBytesFormat([]byte(`{"size": 1e20}`), config.JSON)
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  if _, err := j.GetByteSize("size"); err == nil || strings.Contains(err.Error(), "too large") == false {
+    t.Errorf("Expected 1e20 to be too large, got %v", err)
+  }
+}
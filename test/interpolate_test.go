@@ -0,0 +1,164 @@
+package main
+
+import (
+  "os"
+  "strings"
+  "testing"
+  "app/config"
+)
+
+func TestInterpolation(t *testing.T) {
+  os.Setenv("INTERPOLATION_DB_HOST", "db.example.com")
+  os.Setenv("INTERPOLATION_EMPTY", "")
+
+  defer os.Unsetenv("INTERPOLATION_DB_HOST")
+  defer os.Unsetenv("INTERPOLATION_EMPTY")
+
+  config, err := config.LoadBytesP([]byte(`
+db:
+  host: ${INTERPOLATION_DB_HOST}
+  port: 5432
+  name: app
+url: "postgres://${db.host}:${db.port}/${db.name}"
+port: ${db.port}
+user: ${INTERPOLATION_USER:-admin}
+fallback: ${INTERPOLATION_EMPTY:-${db.name}-fallback}
+price: "$$100 and ${NOT_SET_ANYWHERE}nothing"
+literal: "$${db.host} and $HOME"
+servers:
+  - "${db.host}:80"
+`)).Interpolate()
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  expected := map[string]string{
+    "db.host": "db.example.com",
+    "url": "postgres://db.example.com:5432/app",
+    "user": "admin",
+    "fallback": "app-fallback",
+    "price": "$100 and nothing",
+    "literal": "${db.host} and $HOME",
+    "servers.0": "db.example.com:80",
+  }
+
+  for key, expectedValue := range expected {
+    value, err := config.GetString(key)
+
+    if value != expectedValue {
+      t.Errorf("Expected %q under %s, got %q", expectedValue, key, value)
+    }
+
+    if err != nil {
+      t.Errorf("Expected to find key: %s", key)
+    }
+  }
+
+  port, _ := config.Get("port")
+
+  if port != 5432 {
+    t.Errorf("Expected a whole-value reference to keep its type, got %v (%T)", port, port)
+  }
+}
+
+func TestInterpolationAcrossFiles(t *testing.T) {
+  defaults := config.LoadBytesSectionP([]byte("defaults:\n  host: localhost\n"), "defaults")
+  c := defaults.Merge(config.LoadBytesSectionP([]byte(`
+env_vars:
+  url: "http://${host}:${PORT:-8080}"
+`), section)).InterpolateP()
+
+  expected := "http://localhost:8080"
+  fromConfig := c.GetStringP("url")
+
+  if fromConfig != expected {
+    t.Errorf("Expected %s, got %s", expected, fromConfig)
+  }
+}
+
+func TestNoInterpolationByDefault(t *testing.T) {
+  c, err := config.LoadBytes([]byte("password: pa$$word\ntemplate: \"Hello ${name\"\nhome: ${HOME}\n"))
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  expected := map[string]string{
+    "password": "pa$$word",
+    "template": "Hello ${name",
+    "home": "${HOME}",
+  }
+
+  for key, expectedValue := range expected {
+    if value := c.GetStringP(key); value != expectedValue {
+      t.Errorf("Expected %q under %s, got %q", expectedValue, key, value)
+    }
+  }
+}
+
+func TestInterpolationKeepsOverlaysLiteral(t *testing.T) {
+  os.Setenv("INTERPOLATION_PASSWORD", "pa$$word${x}")
+  defer os.Unsetenv("INTERPOLATION_PASSWORD")
+
+  c := config.LoadBytesP([]byte("password: ${INTERPOLATION_PASSWORD}\nname: app\n")).
+    MergeWithPrefixedEnvVars("INTERPOLATION_")
+
+  c.Set("greeting", "${name}")
+  c = c.InterpolateP()
+
+  if c.GetStringP("password") != "pa$$word${x}" || c.GetStringP("greeting") != "${name}" {
+    t.Errorf("Expected env vars and Set to be taken literally, got %q and %q", c.GetStringP("password"), c.GetStringP("greeting"))
+  }
+}
+
+func TestInterpolationRequiredVariable(t *testing.T) {
+  _, err := config.LoadBytesP([]byte("password: ${INTERPOLATION_PASSWORD:?set INTERPOLATION_PASSWORD to the db password}\n")).Interpolate()
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  } else if strings.Contains(err.Error(), "password: set INTERPOLATION_PASSWORD to the db password") == false {
+    t.Errorf("Expected the message to be reported, got %s", err.Error())
+  }
+
+  _, err = config.LoadBytesP([]byte("password: ${INTERPOLATION_PASSWORD:?}\n")).Interpolate()
+
+  if err == nil || strings.Contains(err.Error(), "INTERPOLATION_PASSWORD is not set") == false {
+    t.Errorf("Expected a default message, got %v", err)
+  }
+}
+
+func TestInterpolationCycle(t *testing.T) {
+  _, err := config.LoadBytesP([]byte("a: ${b}\nb: x${c}\nc: ${a}\n")).Interpolate()
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  } else if strings.Contains(err.Error(), "reference cycle") == false {
+    t.Errorf("Expected the cycle to be reported, got %s", err.Error())
+  }
+}
+
+func TestInterpolationUnclosedReference(t *testing.T) {
+  _, err := config.LoadBytesP([]byte("a: ${b\n")).Interpolate()
+
+  if err == nil || strings.Contains(err.Error(), "missing }") == false {
+    t.Errorf("Expected the missing brace to be reported, got %v", err)
+  }
+}
+
+func TestInterpolationKeepsDotenvQuoting(t *testing.T) {
+  dotenv := config.LoadBytesFormatP([]byte("SINGLE='${HOME}'\nESCAPED=\"\\${HOME}\"\nDB__NAME=app\n"), config.Dotenv)
+  c := dotenv.Merge(config.LoadBytesP([]byte("url: db/${db.name}\n"))).InterpolateP()
+
+  expected := map[string]string{
+    "single": "${HOME}",
+    "escaped": "${HOME}",
+    "url": "db/app",
+  }
+
+  for key, expectedValue := range expected {
+    if value := c.GetStringP(key); value != expectedValue {
+      t.Errorf("Expected %q under %s, got %q", expectedValue, key, value)
+    }
+  }
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+  "os"
+  "flag"
+  "strings"
+  "testing"
+  "testing/fstest"
+  "app/config"
+)
+
+var originFiles = fstest.MapFS{
+  "config.yaml": &fstest.MapFile{Data: []byte(`# Shared settings
+name: app
+db:
+  host: localhost
+  password: hunter2
+  pool:
+    max: 10
+description: |
+  host: not a key
+servers:
+  - host: a
+    port: 80
+  - host: b
+env_vars:
+  Width: 300
+`)},
+  "production.json": &fstest.MapFile{Data: []byte(`{
+  "db": {
+    "pool": {
+      "max": 50
+    }
+  },
+  "servers": ["c"]
+}`)},
+  "overrides.toml": &fstest.MapFile{Data: []byte(`name = "toml"
+
+[db.pool]
+idle = 3
+`)},
+  "local.env": &fstest.MapFile{Data: []byte("# local\nDB__HOST=127.0.0.1\n")},
+}
+
+func expectOrigin(t *testing.T, c *config.Config, key string, expected ...string) {
+  origins := c.Origins(key)
+  described := make([]string, len(origins))
+
+  for i, origin := range origins {
+    described[i] = origin.String()
+  }
+
+  if strings.Join(described, ", ") != strings.Join(expected, ", ") {
+    t.Errorf("Expected %s to come from %v, got %v", key, expected, described)
+  }
+}
+
+func TestOriginsOfFiles(t *testing.T) {
+  c := config.LoadFSP(originFiles, "config.yaml").
+    Merge(config.LoadFSP(originFiles, "production.json")).
+    Merge(config.LoadFSP(originFiles, "overrides.toml")).
+    Merge(config.LoadFSP(originFiles, "local.env"))
+
+  expectOrigin(t, c, "name", "overrides.toml", "config.yaml:2")
+  expectOrigin(t, c, "db.host", "local.env:2", "config.yaml")
+  expectOrigin(t, c, "db.pool.max", "production.json:4", "config.yaml")
+  expectOrigin(t, c, "db.pool.idle", "overrides.toml")
+  expectOrigin(t, c, "servers", "production.json:7", "config.yaml:10")
+  expectOrigin(t, c, "description", "config.yaml:8")
+  expectOrigin(t, c, "unexisting")
+
+  origins := c.Origins("db.pool.max")
+
+  if len(origins) != 2 || origins[0].Value != 50 || origins[1].Value != 10 {
+    t.Errorf("Expected the values to be kept along the origins, got %v", origins)
+  }
+
+  section := config.LoadSectionFSP(originFiles, "config.yaml", "env_vars")
+  expectOrigin(t, section, "width", "config.yaml")
+
+  bytes := config.LoadBytesP([]byte("a: 1\nb:\n  c: 2\n"))
+  expectOrigin(t, bytes, "a", "loaded bytes:1")
+  expectOrigin(t, bytes, "b.c", "loaded bytes")
+}
+
+func TestOriginsOfOverlays(t *testing.T) {
+  os.Setenv("ORIGIN_DB__HOST", "db.internal")
+  defer os.Unsetenv("ORIGIN_DB__HOST")
+
+  fs := flag.NewFlagSet("app", flag.ContinueOnError)
+  fs.Int("db.pool.max", 5, "")
+  fs.Parse([]string{"--db.pool.max=80"})
+
+  c := config.DefaultsFromStructP(defaultedSettings{}).
+    Merge(config.LoadFSP(originFiles, "config.yaml")).
+    MergeWithPrefixedEnvVars("ORIGIN_").
+    Merge(config.FromFlags(fs)).
+    ApplySetP([]string{"servers[1].port=81"}, []string{"name=web"})
+
+  c.Set("width", 1000)
+
+  expectOrigin(t, c, "db.host", "env var ORIGIN_DB__HOST", "config.yaml", "default tag of main.defaultedSettings")
+  expectOrigin(t, c, "db.pool.max", "flag -db.pool.max", "config.yaml", "default tag of main.defaultedSettings")
+  expectOrigin(t, c, "servers.1.port", "--set")
+  expectOrigin(t, c, "name", "--set-string", "config.yaml:2")
+
+  origins := c.Origins("width")
+
+  if len(origins) != 2 || strings.HasPrefix(origins[0].String(), "Set at ") == false || strings.Contains(origins[0].String(), "origin_test.go:") == false {
+    t.Errorf("Expected width to point at the Set call, got %v", origins)
+  }
+}
+
+func TestExplain(t *testing.T) {
+  os.Setenv("ORIGIN_DB__PASSWORD", "s3cr3t")
+  defer os.Unsetenv("ORIGIN_DB__PASSWORD")
+
+  c := config.LoadFSP(originFiles, "config.yaml").
+    Merge(config.LoadFSP(originFiles, "production.json")).
+    MergeWithPrefixedEnvVars("ORIGIN_")
+
+  explanation, err := c.Explain("db")
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  expected := strings.Join([]string{
+    "db.host",
+    `  "localhost" from config.yaml`,
+    "db.password",
+    "  ****** from env var ORIGIN_DB__PASSWORD",
+    "  ****** from config.yaml, overridden",
+    "db.pool.max",
+    "  50 from production.json:4",
+    "  10 from config.yaml, overridden",
+  }, "\n")
+
+  if explanation != expected {
+    t.Errorf("Expected\n%s\ngot\n%s", expected, explanation)
+  }
+
+  _, err = c.Explain("unexisting")
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  }
+}
+
+func TestOriginsOfReplacedSections(t *testing.T) {
+  os.Setenv("PX_DB", "scalar")
+  defer os.Unsetenv("PX_DB")
+
+  file := config.LoadBytesP([]byte("db:\n  host: x\n  port: 1\nname: app\ncache:\n  size: 1\n"))
+  c := file.MergeWithPrefixedEnvVars("PX_")
+
+  explanation := c.ExplainP("db")
+  expected := "db\n  \"scalar\" from env var PX_DB"
+
+  if explanation != expected {
+    t.Errorf("Expected\n%s\ngot\n%s", expected, explanation)
+  }
+
+  c = c.ApplySetP([]string{"db.host=y"}, nil)
+  expectOrigin(t, c, "db.host", "--set")
+  expectOrigin(t, c, "db")
+
+  c = c.Merge(config.LoadBytesP([]byte("name:\n  first: web\n")))
+  expectOrigin(t, c, "name")
+  expectOrigin(t, c, "name.first", "loaded bytes")
+
+  c.Set("cache", map[interface{}]interface{}{"ttl": 5})
+  expectOrigin(t, c, "cache.size")
+
+  if len(c.Origins("cache.ttl")) != 1 {
+    t.Errorf("Expected cache.ttl to come from Set, got %v", c.Origins("cache.ttl"))
+  }
+
+  // Merging maps keeps the keys of both, and their origins
+  merged := file.Merge(config.LoadBytesP([]byte("db:\n  port: 2\n")))
+  expectOrigin(t, merged, "db.host", "loaded bytes")
+  expectOrigin(t, merged, "db.port", "loaded bytes", "loaded bytes")
+}
+
+func TestExplainAfterDottedSet(t *testing.T) {
+  c := config.LoadFSP(originFiles, "config.yaml")
+  c.Set("db.host", "x")
+
+  explanation := c.ExplainP("db.host")
+
+  if strings.HasPrefix(explanation, "db.host\n  \"x\" from ") == false {
+    t.Errorf("Expected x to come from Set, got\n%s", explanation)
+  }
+
+  var settings struct {
+    Db struct {
+      Host string
+      Password string
+    }
+  }
+
+  if err := c.Unmarshal(&settings); err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  if settings.Db.Host != "x" || settings.Db.Password != "hunter2" {
+    t.Errorf("Expected Unmarshal to agree with Explain, got %+v", settings.Db)
+  }
+
+  if c.GetStringMapP("db")["host"] != "x" {
+    t.Errorf("Expected the db section to hold x, got %v", c.GetStringMapP("db"))
+  }
+
+  if strings.Contains(string(c.DumpP(config.YAML)), "db.host") {
+    t.Errorf("Expected no literal db.host key in\n%s", string(c.DumpP(config.YAML)))
+  }
+}
+
+func TestOriginLines(t *testing.T) {
+  cases := []struct {
+    name string
+    contents string
+    format config.Format
+    key string
+    expected string
+  }{
+    {"yaml top-level key", "# comment\nwidth: 1\n", config.YAML, "width", "f:2"},
+    {"yaml nested key has no line", "db:\n  host: x\n", config.YAML, "db.host", "f"},
+    {"yaml quoted key with a colon", "\"a: b\": 1\nc: 2\n", config.YAML, "c", "f:2"},
+    {"yaml flow map", "db: {host: x, port: 1}\nc: 2\n", config.YAML, "c", "f:2"},
+    {"yaml block scalar", "text: |\n  key: not a key\nkey: 1\n", config.YAML, "key", "f:3"},
+    {"yaml anchors", "base: &base\n  a: 1\nother:\n  <<: *base\nc: 1\n", config.YAML, "c", "f:5"},
+    {"yaml list", "servers:\n- a\n- b\nc: 1\n", config.YAML, "c", "f:4"},
+    {"yaml document start", "---\nwidth: 1\n", config.YAML, "width", "f:2"},
+    {"yaml second document is ignored", "width: 1\n---\nheight: 2\n", config.YAML, "width", "f:1"},
+    {"json nested key", "{\n  \"db\": {\n    \"Host\": \"x\"\n  }\n}", config.JSON, "db.Host", "f:3"},
+    {"json list", "{\"a\": [\n  1,\n  2\n],\n\"b\": 1}", config.JSON, "b", "f:5"},
+    {"json string with braces", "{\"a\": \"{\\\"b\\\": 1}\",\n\"c\": 1}", config.JSON, "c", "f:2"},
+    {"toml has no lines", "a = 1\n", config.TOML, "a", "f"},
+    {"dotenv", "# comment\n\nA=1\nDB__HOST=x\n", config.Dotenv, "db.host", "f:4"},
+  }
+
+  extensions := map[config.Format]string{config.YAML: "yaml", config.JSON: "json", config.TOML: "toml", config.Dotenv: "env"}
+
+  for _, c := range cases {
+    name := "f." + extensions[c.format]
+    fsys := fstest.MapFS{name: &fstest.MapFile{Data: []byte(c.contents)}}
+    loaded, err := config.LoadFS(fsys, name)
+
+    if err != nil {
+      t.Errorf("%s: expected no error, got %s", c.name, err.Error())
+      continue
+    }
+
+    origins := loaded.Origins(c.key)
+    expected := strings.Replace(c.expected, "f", name, 1)
+
+    if len(origins) != 1 || origins[0].String() != expected {
+      t.Errorf("%s: expected %s to come from %s, got %v", c.name, c.key, expected, origins)
+    }
+  }
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+  "fmt"
+  "sync"
+  "testing"
+  "app/config"
+)
+
+// Meant to be run with the race detector, see make test_race
+func TestConcurrentGetSetMerge(t *testing.T) {
+  c := config.Defaults(config.ConfigData{"depth": 7}).Merge(config.LoadP(fmt.Sprintf("test/%s", mainFileName)))
+  overlay := config.LoadSectionP(fmt.Sprintf("test/%s", secondaryFileName), productionSection)
+
+  var wg sync.WaitGroup
+  iterations := 200
+
+  for i := 0; i < 4; i++ {
+    wg.Add(5)
+
+    go func(i int) {
+      defer wg.Done()
+
+      for j := 0; j < iterations; j++ {
+        c.Set("width", i * iterations + j)
+        c.Set(fmt.Sprintf("key_%d", i), j)
+      }
+    }(i)
+
+    go func() {
+      defer wg.Done()
+
+      for j := 0; j < iterations; j++ {
+        c.GetIntP("width")
+        c.GetIntP("db.pool.max")
+        c.GetIntP("depth")
+        c.IsDefault("depth")
+      }
+    }()
+
+    go func() {
+      defer wg.Done()
+
+      for j := 0; j < iterations; j++ {
+        merged := c.Merge(overlay)
+        merged.Set("width", j)
+      }
+    }()
+
+    go func() {
+      defer wg.Done()
+
+      for j := 0; j < iterations; j++ {
+        c.MergeWithEnvVars()
+        overlay.Merge(c)
+      }
+    }()
+
+    go func() {
+      defer wg.Done()
+
+      for j := 0; j < iterations; j++ {
+        settings := mainSettings{}
+        c.UnmarshalP(&settings)
+      }
+    }()
+  }
+
+  wg.Wait()
+
+  if c.GetIntP("db.pool.max") != primaryDbPoolMax {
+    t.Errorf("Expected %d, got %d", primaryDbPoolMax, c.GetIntP("db.pool.max"))
+  }
+}
+
+func TestConcurrentSelfMerge(t *testing.T) {
+  c := config.LoadP(fmt.Sprintf("test/%s", mainFileName))
+
+  var wg sync.WaitGroup
+
+  for i := 0; i < 4; i++ {
+    wg.Add(2)
+
+    go func(i int) {
+      defer wg.Done()
+
+      for j := 0; j < 100; j++ {
+        c.Set("width", j)
+      }
+    }(i)
+
+    go func() {
+      defer wg.Done()
+
+      for j := 0; j < 100; j++ {
+        c.Merge(c)
+      }
+    }()
+  }
+
+  wg.Wait()
+}
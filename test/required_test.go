@@ -0,0 +1,62 @@
+package main
+
+import (
+  "fmt"
+  "strings"
+  "testing"
+  "app/config"
+)
+
+func TestRequiredKeys(t *testing.T) {
+  required := config.RequiredKeys{"width", "db.host", "db.pool.max"}
+  c := config.LoadP(fmt.Sprintf("test/%s", mainFileName))
+
+  err := required.Check(c)
+
+  if err != nil {
+    t.Errorf("Expected no error, got %s", err.Error())
+  }
+}
+
+func TestRequiredKeysReportsEveryMissingKey(t *testing.T) {
+  required := config.RequiredKeys{"width", "api_key", "db.port", "db.pool.max", "cache.size"}
+  c := config.LoadP(fmt.Sprintf("test/%s", mainFileName)).MergeWithEnvVars()
+
+  err := required.Check(c)
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+    return
+  }
+
+  expected := "Missing required keys: api_key, db.port, cache.size"
+
+  if strings.Contains(err.Error(), expected) == false {
+    t.Errorf("Expected %s, got %s", expected, err.Error())
+  }
+}
+
+func TestRequiredKeysAcceptsDefaults(t *testing.T) {
+  required := config.RequiredKeys{"db.port"}
+  defaults := config.Defaults(config.ConfigData{"db": map[string]interface{}{"port": 5432}})
+  c := defaults.Merge(config.LoadP(fmt.Sprintf("test/%s", mainFileName)))
+
+  err := required.Check(c)
+
+  if err != nil {
+    t.Errorf("Expected no error, got %s", err.Error())
+  }
+}
+
+func TestRequiredKeysCheckP(t *testing.T) {
+  defer func(){
+    r := recover()
+
+    if r == nil {
+      t.Errorf("Expected to be recovering from a panic here")
+    }
+  }()
+
+  required := config.RequiredKeys{"api_key"}
+  required.CheckP(config.LoadP(fmt.Sprintf("test/%s", mainFileName)))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+  "os"
+  "fmt"
+  "strings"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+  "app/config"
+)
+
+func writeSecret(t *testing.T, dir string, name string, contents string) string {
+  path := filepath.Join(dir, name)
+  err := ioutil.WriteFile(path, []byte(contents), 0600)
+
+  if err != nil {
+    t.Fatalf("Could not write %s", path)
+  }
+
+  return path
+}
+
+func TestMergeWithEnvVarFiles(t *testing.T) {
+  dir, err := ioutil.TempDir("", "secrets")
+
+  if err != nil {
+    t.Fatalf("Could not create a temp dir")
+  }
+
+  defer os.RemoveAll(dir)
+
+  os.Setenv("SECRETS_DB_PASSWORD_FILE", writeSecret(t, dir, "db", "s3cr3t\n"))
+  os.Setenv("SECRETS_DB__API_TOKEN_FILE", writeSecret(t, dir, "token", "line one\nline two\r\n"))
+  os.Setenv("SECRETS_DB_PASSWORD", "from env var")
+
+  defer os.Unsetenv("SECRETS_DB_PASSWORD_FILE")
+  defer os.Unsetenv("SECRETS_DB__API_TOKEN_FILE")
+  defer os.Unsetenv("SECRETS_DB_PASSWORD")
+
+  c := config.LoadP(fmt.Sprintf("test/%s", mainFileName)).MergeWithPrefixedEnvVars("SECRETS_")
+  c, loadErr := c.MergeWithPrefixedEnvVarFiles("SECRETS_")
+
+  if loadErr != nil {
+    t.Fatalf("Expected no error, got %s", loadErr.Error())
+  }
+
+  expected := map[string]string{
+    "db_password": "s3cr3t",
+    "db.api_token": "line one\nline two",
+    "db.host": primaryDbHost,
+  }
+
+  for key, expectedValue := range expected {
+    value, err := c.GetString(key)
+
+    if value != expectedValue {
+      t.Errorf("Expected %q under %s, got %q", expectedValue, key, value)
+    }
+
+    if err != nil {
+      t.Errorf("Expected to find key: %s", key)
+    }
+  }
+
+  _, getErr := c.Get("db_password_file")
+
+  if getErr != nil {
+    t.Errorf("Expected the plain env var merge to keep db_password_file")
+  }
+}
+
+func TestMergeWithEnvVarFilesUnreadable(t *testing.T) {
+  os.Setenv("SECRETS_MISSING_FILE", "/nonexistent/secret")
+  defer os.Unsetenv("SECRETS_MISSING_FILE")
+
+  _, err := config.LoadP(fmt.Sprintf("test/%s", mainFileName)).MergeWithPrefixedEnvVarFiles("SECRETS_")
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  } else if strings.Contains(err.Error(), "Could not read SECRETS_MISSING_FILE") == false || strings.Contains(err.Error(), "/nonexistent/secret") == false {
+    t.Errorf("Expected the env var and the file to be named, got %s", err.Error())
+  }
+
+  os.Setenv("SECRETS_MISSING_FILE", "")
+  _, err = config.LoadP(fmt.Sprintf("test/%s", mainFileName)).MergeWithPrefixedEnvVarFiles("SECRETS_")
+
+  if err == nil || strings.Contains(err.Error(), "no file given") == false {
+    t.Errorf("Expected an empty path to be reported, got %v", err)
+  }
+}
+
+func TestMergeWithEnvVarFilesRequiresPrefix(t *testing.T) {
+  os.Setenv("UNRELATED_CERT_FILE", "/nonexistent/cert")
+  defer os.Unsetenv("UNRELATED_CERT_FILE")
+
+  _, err := config.LoadP(fmt.Sprintf("test/%s", mainFileName)).MergeWithPrefixedEnvVarFiles("")
+
+  if err == nil || strings.Contains(err.Error(), "a prefix is required") == false {
+    t.Errorf("Expected an empty prefix to be refused, got %v", err)
+  }
+
+  c, err := config.LoadP(fmt.Sprintf("test/%s", mainFileName)).MergeWithPrefixedEnvVarFiles("SECRETS_")
+
+  if err != nil {
+    t.Fatalf("Expected variables outside the prefix to be ignored, got %s", err.Error())
+  }
+
+  if _, getErr := c.Get("unrelated_cert"); getErr == nil {
+    t.Errorf("Expected unrelated_cert not to be read")
+  }
+}
+
+func TestEnvVarFilesAreSecret(t *testing.T) {
+  dir, err := ioutil.TempDir("", "secrets")
+
+  if err != nil {
+    t.Fatalf("Could not create a temp dir")
+  }
+
+  defer os.RemoveAll(dir)
+
+  os.Setenv("SECRETS_API_KEY_FILE", writeSecret(t, dir, "key", "vm\n"))
+  defer os.Unsetenv("SECRETS_API_KEY_FILE")
+
+  before := config.LoadBytesP([]byte("api_key: old\n"))
+  c := before.MergeWithPrefixedEnvVarFilesP("SECRETS_")
+
+  if c.IsSecret("api_key") == false {
+    t.Errorf("Expected api_key to be marked secret")
+  }
+
+  dumped := string(c.DumpP(config.YAML))
+  explanation := c.ExplainP("api_key")
+  diff := config.Diff(before, c).String()
+
+  for _, output := range []string{dumped, c.String(), fmt.Sprintf("%#v", c), explanation, diff} {
+    if strings.Contains(output, "vm") || strings.Contains(output, config.Redacted) == false {
+      t.Errorf("Expected api_key to be redacted, got %s", output)
+    }
+  }
+}
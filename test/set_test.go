@@ -0,0 +1,174 @@
+package main
+
+import (
+  "fmt"
+  "flag"
+  "strings"
+  "testing"
+  "app/config"
+)
+
+func TestParseSet(t *testing.T) {
+  overlay, err := config.ParseSet(
+    []string{"width=300,db.pool.max=90", "is_awesome=true", "ratio=0.75", "zip=0123", "nothing=null", "servers[1].port=81"},
+    []string{"version=1.10", "db.name=true"},
+  )
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  expected := map[string]interface{}{
+    "width": 300,
+    "db.pool.max": 90,
+    "is_awesome": true,
+    "ratio": 0.75,
+    "zip": "0123",
+    "nothing": nil,
+    "servers.1.port": 81,
+    "version": "1.10",
+    "db.name": "true",
+  }
+
+  for key, expectedValue := range expected {
+    value, err := overlay.Get(key)
+
+    if value != expectedValue {
+      t.Errorf("Expected %v (%T) under %s, got %v (%T)", expectedValue, expectedValue, key, value, value)
+    }
+
+    if err != nil {
+      t.Errorf("Expected to find key: %s", key)
+    }
+  }
+
+  servers := overlay.GetP("servers").([]interface{})
+
+  if len(servers) != 2 || servers[0] != nil {
+    t.Errorf("Expected the list to be padded with nil, got %v", servers)
+  }
+}
+
+func TestParseSetListsAndEscapes(t *testing.T) {
+  overlay := config.ParseSetP([]string{`hosts={a.example.com,b.example.com},ports={80,443}`, `name=a\,b`, `annotations.kubernetes\.io/name=web`}, nil)
+
+  hosts := overlay.GetP("hosts").([]interface{})
+
+  if len(hosts) != 2 || hosts[1] != "b.example.com" {
+    t.Errorf("Expected two hosts, got %v", hosts)
+  }
+
+  ports := overlay.GetP("ports").([]interface{})
+
+  if len(ports) != 2 || ports[1] != 443 {
+    t.Errorf("Expected two typed ports, got %v", ports)
+  }
+
+  expectedName := "a,b"
+  nameFromConfig := overlay.GetStringP("name")
+
+  if nameFromConfig != expectedName {
+    t.Errorf("Expected %s, got %s", expectedName, nameFromConfig)
+  }
+
+  annotations := overlay.GetP("annotations").(map[interface{}]interface{})
+
+  if annotations["kubernetes.io/name"] != "web" {
+    t.Errorf("Expected escaped dot to stay in the key, got %v", annotations)
+  }
+}
+
+func TestParseSetMergedOverFile(t *testing.T) {
+  overlay := config.ParseSetP([]string{"db.pool.max=90"}, nil)
+  config := config.LoadP(fmt.Sprintf("test/%s", mainFileName)).Merge(overlay)
+
+  expectedMax := 90
+  maxFromConfig := config.GetIntP("db.pool.max")
+
+  if maxFromConfig != expectedMax {
+    t.Errorf("Expected %d, got %d", expectedMax, maxFromConfig)
+  }
+
+  expectedHost := primaryDbHost
+  hostFromConfig := config.GetStringP("db.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+}
+
+func TestApplySetPatchesListItems(t *testing.T) {
+  base := config.LoadBytesP([]byte("servers:\n  - host: alpha\n    port: 80\n  - host: beta\n    port: 80\n"))
+  patched := base.ApplySetP([]string{"servers[1].port=81"}, nil)
+
+  expectedPort := 81
+  portFromConfig := patched.GetIntP("servers.1.port")
+
+  if portFromConfig != expectedPort {
+    t.Errorf("Expected %d, got %d", expectedPort, portFromConfig)
+  }
+
+  expectedHost := "alpha"
+  hostFromConfig := patched.GetStringP("servers.0.host")
+
+  if hostFromConfig != expectedHost {
+    t.Errorf("Expected %s, got %s", expectedHost, hostFromConfig)
+  }
+
+  if base.GetIntP("servers.1.port") != 80 {
+    t.Errorf("Expected the base config to stay untouched")
+  }
+}
+
+func TestParseSetErrors(t *testing.T) {
+  invalid := map[string]string{
+    "width": "has no value",
+    "a,b=c": "has no value",
+    "servers[x].port=1": "invalid list index",
+    "servers[1.port=1": "missing ]",
+    "servers[99999999]=1": "is higher than",
+    "[0]=1": "has to start with a name",
+    "hosts={a,b": "missing }",
+  }
+
+  for expression, message := range invalid {
+    _, err := config.ParseSet([]string{expression}, nil)
+
+    if err == nil {
+      t.Errorf("Expected to see error for %q", expression)
+    } else if strings.Contains(err.Error(), message) == false {
+      t.Errorf("Expected error for %q to contain %q, got %s", expression, message, err.Error())
+    }
+  }
+
+  _, err := config.ParseSet(nil, []string{"width"})
+
+  if err == nil || strings.Contains(err.Error(), `Could not parse --set-string "width"`) == false {
+    t.Errorf("Expected the error to name --set-string, got %v", err)
+  }
+
+  _, err = config.ParseSet([]string{"width"}, nil)
+
+  if err == nil || strings.Contains(err.Error(), `Could not parse --set "width"`) == false {
+    t.Errorf("Expected the error to name --set, got %v", err)
+  }
+}
+
+func TestSetValuesFlag(t *testing.T) {
+  var values, stringValues config.SetValues
+
+  fs := flag.NewFlagSet("app", flag.ContinueOnError)
+  fs.Var(&values, "set", "")
+  fs.Var(&stringValues, "set-string", "")
+  fs.Parse([]string{"--set", "width=300", "--set", "db.pool.max=5", "--set-string", "tag=007"})
+
+  overlay := config.ParseSetP(values, stringValues)
+
+  if overlay.GetIntP("width") != 300 || overlay.GetIntP("db.pool.max") != 5 {
+    t.Errorf("Expected both --set flags to be applied, got %v", overlay.Data)
+  }
+
+  if overlay.GetStringP("tag") != "007" {
+    t.Errorf("Expected %s, got %s", "007", overlay.GetStringP("tag"))
+  }
+}
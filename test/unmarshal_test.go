@@ -0,0 +1,224 @@
+package main
+
+import (
+  "fmt"
+  "time"
+  "strings"
+  "testing"
+  "app/config"
+)
+
+type poolSettings struct {
+  Max int
+  Idle *int
+}
+
+type dbSettings struct {
+  Host string `config:"host"`
+  Pool poolSettings `config:"pool"`
+}
+
+type mainSettings struct {
+  Width int `config:"width"`
+  Height float64
+  Numbers []string `config:"numbers"`
+  IsAwesome bool `config:"is_awesome"`
+  IsTerrible bool `config:"is_terrible"`
+  Db dbSettings `config:"db"`
+  Ignored string `config:"-"`
+  Missing string `config:"missing"`
+}
+
+func TestUnmarshal(t *testing.T) {
+  config := config.LoadP(fmt.Sprintf("test/%s", mainFileName))
+  settings := mainSettings{Ignored: "untouched"}
+
+  err := config.Unmarshal(&settings)
+
+  if err != nil {
+    t.Errorf("Expected no error, got %s", err.Error())
+  }
+
+  if settings.Width != primaryWidth {
+    t.Errorf("Expected %d, got %d", primaryWidth, settings.Width)
+  }
+
+  if settings.Height != primaryHeight {
+    t.Errorf("Expected %f, got %f", primaryHeight, settings.Height)
+  }
+
+  if len(settings.Numbers) != len(numbers) {
+    t.Errorf("Expected %v, got %v", numbers, settings.Numbers)
+  } else {
+    for i, value := range settings.Numbers {
+      if value != numbers[i] {
+        t.Errorf("Expected %v at index %v, got %v", numbers[i], i, value)
+      }
+    }
+  }
+
+  if settings.IsAwesome != primaryIsAwesome {
+    t.Errorf("Expected %t, got %t", primaryIsAwesome, settings.IsAwesome)
+  }
+
+  if settings.Db.Host != primaryDbHost {
+    t.Errorf("Expected %s, got %s", primaryDbHost, settings.Db.Host)
+  }
+
+  if settings.Db.Pool.Max != primaryDbPoolMax {
+    t.Errorf("Expected %d, got %d", primaryDbPoolMax, settings.Db.Pool.Max)
+  }
+
+  if settings.Db.Pool.Idle != nil {
+    t.Errorf("Expected pointer to missing key to stay nil, got %v", *settings.Db.Pool.Idle)
+  }
+
+  if settings.Ignored != "untouched" {
+    t.Errorf("Expected ignored field to stay untouched, got %s", settings.Ignored)
+  }
+
+  if settings.Missing != "" {
+    t.Errorf("Expected missing key to leave zero value, got %s", settings.Missing)
+  }
+}
+
+func TestUnmarshalConvertsEnvVarStrings(t *testing.T) {
+  config := config.LoadP(fmt.Sprintf("test/%s", mainFileName)).MergeWithPrefixedEnvVars(envVarPrefix)
+  settings := mainSettings{}
+
+  err := config.Unmarshal(&settings)
+
+  if err != nil {
+    t.Errorf("Expected no error, got %s", err.Error())
+  }
+
+  if settings.Width != prefixedWidth {
+    t.Errorf("Expected %d, got %d", prefixedWidth, settings.Width)
+  }
+
+  if settings.Db.Pool.Idle == nil || *settings.Db.Pool.Idle != envDbPoolIdle {
+    t.Errorf("Expected %d, got %v", envDbPoolIdle, settings.Db.Pool.Idle)
+  }
+}
+
+func TestUnmarshalSection(t *testing.T) {
+  config := config.LoadP(fmt.Sprintf("test/%s", mainFileName))
+  pool := poolSettings{}
+
+  err := config.UnmarshalSection("db.pool", &pool)
+
+  if err != nil {
+    t.Errorf("Expected no error, got %s", err.Error())
+  }
+
+  if pool.Max != primaryDbPoolMax {
+    t.Errorf("Expected %d, got %d", primaryDbPoolMax, pool.Max)
+  }
+
+  err = config.UnmarshalSection("db.whatever", &pool)
+
+  if err == nil {
+    t.Errorf("Expected not to find key: db.whatever")
+  }
+}
+
+func TestUnmarshalCollectionsAndDurations(t *testing.T) {
+  type settings struct {
+    Timeout time.Duration `config:"timeout"`
+    Started time.Time `config:"started"`
+    Hosts []string `config:"hosts"`
+    Ports [2]int `config:"ports"`
+    Limits map[string]int `config:"limits"`
+    Extra interface{} `config:"extra"`
+  }
+
+  c := &config.Config{Data: config.ConfigData{
+    "timeout": "1m30s",
+    "started": "2019-02-01T10:00:00Z",
+    "hosts": "a.example.com, b.example.com",
+    "ports": []interface{}{80, "443"},
+    "limits": map[interface{}]interface{}{"cpu": 2, "memory": "512"},
+    "extra": map[interface{}]interface{}{"any": "thing"},
+  }}
+
+  s := settings{}
+  err := c.Unmarshal(&s)
+
+  if err != nil {
+    t.Errorf("Expected no error, got %s", err.Error())
+  }
+
+  if s.Timeout != 90 * time.Second {
+    t.Errorf("Expected %v, got %v", 90 * time.Second, s.Timeout)
+  }
+
+  expectedStarted := time.Date(2019, 2, 1, 10, 0, 0, 0, time.UTC)
+
+  if s.Started.Equal(expectedStarted) == false {
+    t.Errorf("Expected %v, got %v", expectedStarted, s.Started)
+  }
+
+  if len(s.Hosts) != 2 || s.Hosts[0] != "a.example.com" || s.Hosts[1] != "b.example.com" {
+    t.Errorf("Expected two hosts, got %v", s.Hosts)
+  }
+
+  if s.Ports != [2]int{80, 443} {
+    t.Errorf("Expected [80 443], got %v", s.Ports)
+  }
+
+  if s.Limits["cpu"] != 2 || s.Limits["memory"] != 512 {
+    t.Errorf("Expected map[cpu:2 memory:512], got %v", s.Limits)
+  }
+
+  if s.Extra.(map[interface{}]interface{})["any"] != "thing" {
+    t.Errorf("Expected map[any:thing], got %v", s.Extra)
+  }
+}
+
+func TestUnmarshalReportsEveryFailure(t *testing.T) {
+  c := &config.Config{Data: config.ConfigData{
+    "width": "wide",
+    "is_awesome": "very",
+    "db": map[interface{}]interface{}{
+      "pool": map[interface{}]interface{}{
+        "max": 1.5,
+      },
+    },
+  }}
+
+  err := c.Unmarshal(&mainSettings{})
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+    return
+  }
+
+  for _, path := range []string{"width", "is_awesome", "db.pool.max"} {
+    if strings.Contains(err.Error(), fmt.Sprintf("%s: ", path)) == false {
+      t.Errorf("Expected error to mention %s, got %s", path, err.Error())
+    }
+  }
+}
+
+func TestUnmarshalNonPointer(t *testing.T) {
+  config := config.LoadP(fmt.Sprintf("test/%s", mainFileName))
+
+  err := config.Unmarshal(mainSettings{})
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  }
+}
+
+func TestUnmarshalPFailure(t *testing.T) {
+  defer func(){
+    r := recover()
+
+    if r == nil {
+      t.Errorf("Expected to be recovering from a panic here")
+    }
+  }()
+
+  c := &config.Config{Data: config.ConfigData{"width": "wide"}}
+  c.UnmarshalP(&mainSettings{})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+  "fmt"
+  "time"
+  "strings"
+  "testing"
+  "app/config"
+)
+
+type validatedDb struct {
+  Host string `config:"host" validate:"required,hostport"`
+  Name string `config:"name" validate:"required"`
+}
+
+type validatedSettings struct {
+  Port int `config:"port" validate:"required,min=1,max=65535"`
+  Mode string `config:"mode" validate:"oneof=development staging production"`
+  Name string `config:"name" validate:"min=3,regex=^[a-z]+(,[a-z]+)*$"`
+  Endpoint string `config:"endpoint" validate:"url"`
+  Timeout time.Duration `config:"timeout" validate:"max=1m"`
+  Tags []string `config:"tags" validate:"max=2"`
+  Db validatedDb `config:"db"`
+}
+
+func TestUnmarshalValid(t *testing.T) {
+  c := &config.Config{Data: config.ConfigData{
+    "port": "8080",
+    "mode": "staging",
+    "name": "api,web",
+    "endpoint": "https://example.com/hook",
+    "timeout": "30s",
+    "tags": []interface{}{"a", "b"},
+    "db": map[interface{}]interface{}{
+      "host": "localhost:5432",
+      "name": "app",
+    },
+  }}
+
+  err := c.Unmarshal(&validatedSettings{})
+
+  if err != nil {
+    t.Errorf("Expected no error, got %s", err.Error())
+  }
+}
+
+func TestUnmarshalReportsEveryViolation(t *testing.T) {
+  c := &config.Config{Data: config.ConfigData{
+    "port": 70000,
+    "mode": "testing",
+    "name": "API",
+    "endpoint": "/hook",
+    "timeout": "5m",
+    "tags": "a,b,c",
+    "db": map[interface{}]interface{}{
+      "host": "localhost",
+    },
+  }}
+
+  err := c.Unmarshal(&validatedSettings{})
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+    return
+  }
+
+  expected := []string{
+    "port: must be at most 65535",
+    "mode: must be one of [development staging production]",
+    "name: must match",
+    "endpoint: must be an absolute url",
+    "timeout: must be at most 1m",
+    "tags: must have a length of at most 2",
+    "db.host: must be host:port",
+    "db.name: is required",
+  }
+
+  for _, message := range expected {
+    if strings.Contains(err.Error(), message) == false {
+      t.Errorf("Expected error to contain %q, got %s", message, err.Error())
+    }
+  }
+}
+
+func TestUnmarshalRequiredInMissingSection(t *testing.T) {
+  c := &config.Config{Data: config.ConfigData{}}
+
+  err := c.Unmarshal(&validatedSettings{})
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+    return
+  }
+
+  for _, path := range []string{"port", "db.host", "db.name"} {
+    if strings.Contains(err.Error(), fmt.Sprintf("%s: is required", path)) == false {
+      t.Errorf("Expected error to report %s as required, got %s", path, err.Error())
+    }
+  }
+
+  if strings.Contains(err.Error(), "mode:") {
+    t.Errorf("Expected optional keys not to be validated when missing, got %s", err.Error())
+  }
+}
+
+func TestUnmarshalOptionalPointerSection(t *testing.T) {
+  type inner struct {
+    Host string `config:"host" validate:"required"`
+  }
+
+  type settings struct {
+    Db *inner `config:"db"`
+  }
+
+  s := settings{}
+  err := (&config.Config{Data: config.ConfigData{}}).Unmarshal(&s)
+
+  if err != nil {
+    t.Errorf("Expected a missing pointer section to be optional, got %s", err.Error())
+  }
+
+  if s.Db != nil {
+    t.Errorf("Expected the pointer to stay nil, got %v", s.Db)
+  }
+
+  err = (&config.Config{Data: config.ConfigData{"db": map[interface{}]interface{}{}}}).Unmarshal(&s)
+
+  if err == nil || strings.Contains(err.Error(), "db.host: is required") == false {
+    t.Errorf("Expected a present pointer section to be validated, got %v", err)
+  }
+}
+
+func TestUnmarshalUnknownRule(t *testing.T) {
+  type settings struct {
+    Port int `config:"port" validate:"positive"`
+  }
+
+  c := &config.Config{Data: config.ConfigData{"port": 1}}
+  err := c.Unmarshal(&settings{})
+
+  if err == nil || strings.Contains(err.Error(), "unknown validation rule") == false {
+    t.Errorf("Expected unknown rule to be reported, got %v", err)
+  }
+}
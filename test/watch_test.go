@@ -0,0 +1,180 @@
+package main
+
+import (
+  "os"
+  "sync"
+  "time"
+  "math/rand"
+  "sync/atomic"
+  "testing"
+  "io/ioutil"
+  "path/filepath"
+  "app/config"
+  "github.com/renra/go-errtrace/errtrace"
+)
+
+func watchedFile(t *testing.T) (string, func()) {
+  dir, err := ioutil.TempDir("", "config")
+
+  if err != nil {
+    t.Fatalf("Could not create temp dir: %s", err.Error())
+  }
+
+  path := filepath.Join(dir, "watched.yaml")
+  writeYaml(path, map[string]interface{}{"width": 100})
+
+  return path, func() {
+    os.RemoveAll(dir)
+  }
+}
+
+func TestWatch(t *testing.T) {
+  interval := config.WatchInterval
+  config.WatchInterval = 10 * time.Millisecond
+
+  defer func() {
+    config.WatchInterval = interval
+  }()
+
+  path, cleanup := watchedFile(t)
+  defer cleanup()
+
+  watcher, err := config.Watch(func() (*config.Config, *errtrace.Error) {
+    return config.Load(path)
+  }, path)
+
+  if err != nil {
+    t.Fatalf("Expected no error, got %s", err.Error())
+  }
+
+  defer watcher.Stop()
+
+  changes := make(chan [2]*config.Config, 1)
+
+  watcher.OnChange(func(old *config.Config, new *config.Config) {
+    changes <- [2]*config.Config{old, new}
+  })
+
+  if watcher.Config().GetIntP("width") != 100 {
+    t.Errorf("Expected %d, got %d", 100, watcher.Config().GetIntP("width"))
+  }
+
+  writeYaml(path, map[string]interface{}{"width": 2000})
+
+  select {
+    case change := <-changes:
+      if change[0].GetIntP("width") != 100 {
+        t.Errorf("Expected old width %d, got %d", 100, change[0].GetIntP("width"))
+      }
+
+      if change[1].GetIntP("width") != 2000 {
+        t.Errorf("Expected new width %d, got %d", 2000, change[1].GetIntP("width"))
+      }
+    case <-time.After(2 * time.Second):
+      t.Fatalf("Expected to be notified about the change")
+  }
+
+  if watcher.Config().GetIntP("width") != 2000 {
+    t.Errorf("Expected %d, got %d", 2000, watcher.Config().GetIntP("width"))
+  }
+}
+
+func TestWatchKeepsLastGoodConfig(t *testing.T) {
+  interval := config.WatchInterval
+  config.WatchInterval = 10 * time.Millisecond
+
+  defer func() {
+    config.WatchInterval = interval
+  }()
+
+  path, cleanup := watchedFile(t)
+  defer cleanup()
+
+  watcher := config.WatchP(func() (*config.Config, *errtrace.Error) {
+    return config.Load(path)
+  }, path)
+
+  defer watcher.Stop()
+
+  errors := make(chan *errtrace.Error, 1)
+
+  watcher.OnError(func(err *errtrace.Error) {
+    errors <- err
+  })
+
+  watcher.OnChange(func(old *config.Config, new *config.Config) {
+    t.Errorf("Expected no change after a broken file")
+  })
+
+  ioutil.WriteFile(path, []byte("width: [unclosed"), 0644)
+
+  select {
+    case <-errors:
+    case <-time.After(2 * time.Second):
+      t.Fatalf("Expected to be notified about the error")
+  }
+
+  if watcher.Config().GetIntP("width") != 100 {
+    t.Errorf("Expected %d, got %d", 100, watcher.Config().GetIntP("width"))
+  }
+}
+
+func TestWatchFailingInitialLoad(t *testing.T) {
+  watcher, err := config.Watch(func() (*config.Config, *errtrace.Error) {
+    return config.Load("whatever.yaml")
+  }, "whatever.yaml")
+
+  if err == nil {
+    t.Errorf("Expected to see error here")
+  }
+
+  if watcher != nil {
+    t.Errorf("Expected watcher to be nil")
+  }
+}
+
+func TestWatchConcurrentReloads(t *testing.T) {
+  var loads int32
+
+  watcher := config.WatchP(func() (*config.Config, *errtrace.Error) {
+    version := atomic.AddInt32(&loads, 1)
+    time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+
+    return &config.Config{Data: config.ConfigData{"version": int(version)}}, nil
+  })
+
+  defer watcher.Stop()
+
+  var mutex sync.Mutex
+  changes := [][2]int{}
+
+  watcher.OnChange(func(old *config.Config, new *config.Config) {
+    mutex.Lock()
+    defer mutex.Unlock()
+
+    changes = append(changes, [2]int{old.GetIntP("version"), new.GetIntP("version")})
+  })
+
+  var wg sync.WaitGroup
+
+  for i := 0; i < 20; i++ {
+    wg.Add(1)
+
+    go func() {
+      defer wg.Done()
+      watcher.Reload()
+    }()
+  }
+
+  wg.Wait()
+
+  if watcher.Config().GetIntP("version") != int(atomic.LoadInt32(&loads)) {
+    t.Errorf("Expected the last load to win, got version %d of %d", watcher.Config().GetIntP("version"), loads)
+  }
+
+  for _, change := range changes {
+    if change[1] != change[0] + 1 {
+      t.Errorf("Expected every change to follow the previous config, got %d -> %d", change[0], change[1])
+    }
+  }
+}